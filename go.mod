@@ -3,9 +3,14 @@ module sigs.k8s.io/e2e-framework
 go 1.16
 
 require (
+	github.com/blang/semver/v4 v4.0.0
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/vladimirvivien/gexe v0.1.0
 	k8s.io/api v0.21.1
 	k8s.io/apimachinery v0.21.1
 	k8s.io/client-go v0.21.1
+	k8s.io/klog/v2 v2.8.0
 	sigs.k8s.io/controller-runtime v0.9.0
+	sigs.k8s.io/kustomize/api v0.8.8
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -0,0 +1,37 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// NewFakeClient returns a Client backed by an in-memory fake client, from
+// the controller-runtime fake package, initialized with objs. It can be used
+// to unit test environment and feature functions without a cluster (see
+// envconf.Config.WithFakeClient). The fake client only supports the object
+// operations: operations using the REST config, such as Resources.GetLogs
+// or Resources.ExecInPod, fail.
+func NewFakeClient(objs ...runtime.Object) Client {
+	cfg := &rest.Config{}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(objs...).Build()
+	return &client{cfg: cfg, resources: resources.NewWithClient(cfg, fakeClient)}
+}
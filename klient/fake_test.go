@@ -0,0 +1,54 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewFakeClient(t *testing.T) {
+	seeded := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "seeded", Namespace: "default"},
+		Data:       map[string]string{"key": "value"},
+	}
+	client := NewFakeClient(seeded)
+
+	var cm corev1.ConfigMap
+	if err := client.Resources().Get(context.TODO(), "seeded", "default", &cm); err != nil {
+		t.Fatal(err)
+	}
+	if cm.Data["key"] != "value" {
+		t.Errorf("unexpected seeded configmap data %v", cm.Data)
+	}
+
+	created := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "created", Namespace: "default"}}
+	if err := client.Resources().Create(context.TODO(), created); err != nil {
+		t.Fatal(err)
+	}
+
+	var list corev1.ConfigMapList
+	if err := client.Resources("default").List(context.TODO(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 {
+		t.Errorf("expected 2 configmaps, got %d", len(list.Items))
+	}
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// fieldManager is the field manager used for server-side apply requests
+const fieldManager = "e2e-framework"
+
+type applyOptions struct {
+	namespace        string
+	createOrUpdate   bool
+	kustomizeVersion string
+	force            bool
+}
+
+// ApplyOption is used to provide additional arguments to the apply calls.
+type ApplyOption func(*applyOptions)
+
+// WithNamespace sets the namespace of all the namespaced objects applied,
+// overriding the namespace set in their manifests.
+func WithNamespace(ns string) ApplyOption {
+	return func(o *applyOptions) { o.namespace = ns }
+}
+
+// WithCreateOrUpdate applies objects by creating them, or updating them when
+// they already exist, instead of using server-side apply.
+func WithCreateOrUpdate() ApplyOption {
+	return func(o *applyOptions) { o.createOrUpdate = true }
+}
+
+// WithForce sets whether Apply forces the ownership of the fields
+// conflicting with the fields owned by other field managers.
+func WithForce(force bool) ApplyOption {
+	return func(o *applyOptions) { o.force = force }
+}
+
+// Apply applies obj using server-side apply, which tracks the ownership of the
+// applied fields by fieldManager. Unless WithForce is set, Apply fails when the
+// applied fields conflict with fields owned by other field managers. The applied
+// object is stored in obj. When the TypeMeta of obj is not set, it is resolved
+// from the scheme.
+func (r *Resources) Apply(ctx context.Context, obj k8s.Object, fieldManager string, opts ...ApplyOption) error {
+	o := &applyOptions{}
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	if obj.GetObjectKind().GroupVersionKind().Empty() {
+		gvk, err := apiutil.GVKForObject(obj, r.scheme)
+		if err != nil {
+			return fmt.Errorf("apply %s: %w", obj.GetName(), err)
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+
+	patchOpts := []cr.PatchOption{cr.FieldOwner(fieldManager)}
+	if o.force {
+		patchOpts = append(patchOpts, cr.ForceOwnership)
+	}
+	return r.client.Patch(ctx, obj, cr.Apply, patchOpts...)
+}
+
+// ApplyYAMLFile applies the objects of the YAML manifest file at path,
+// which may contain multiple documents (see ApplyYAMLBytes).
+func (r *Resources) ApplyYAMLFile(ctx context.Context, path string, opts ...ApplyOption) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("apply yaml file: %w", err)
+	}
+	return r.ApplyYAMLBytes(ctx, data, opts...)
+}
+
+// ApplyYAMLBytes applies, in order, the objects of the YAML manifest which may
+// contain multiple documents separated by `---` (see ApplyFromReader).
+func (r *Resources) ApplyYAMLBytes(ctx context.Context, data []byte, opts ...ApplyOption) error {
+	return r.ApplyFromReader(ctx, bytes.NewReader(data), opts...)
+}
+
+// ApplyFromReader applies, in order, the objects of the YAML manifest read
+// from reader, which may contain multiple documents separated by `---`.
+// Objects of kinds registered in the scheme are decoded into typed objects,
+// other objects are applied as unstructured objects. Objects are applied using
+// server-side apply, unless WithCreateOrUpdate is set.
+func (r *Resources) ApplyFromReader(ctx context.Context, reader io.Reader, opts ...ApplyOption) error {
+	o := &applyOptions{}
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	objs, err := r.decodeYAML(reader)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		if err := r.applyObject(ctx, obj, o); err != nil {
+			return fmt.Errorf("apply %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// CreateFromReader creates, in order, the objects of the YAML manifest read
+// from reader, which may contain multiple documents separated by `---`. It
+// fails on the first object that cannot be created, i.e. when it already exists.
+func (r *Resources) CreateFromReader(ctx context.Context, reader io.Reader, opts ...CreateOption) error {
+	objs, err := r.decodeYAML(reader)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		if err := r.Create(ctx, obj, opts...); err != nil {
+			return fmt.Errorf("create %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// DeleteFromReader deletes the objects of the YAML manifest read from reader,
+// which may contain multiple documents separated by `---`. Objects are deleted
+// in the reverse order of the manifest, so that objects declared first (i.e.
+// namespaces) are deleted last. Objects that do not exist are ignored.
+func (r *Resources) DeleteFromReader(ctx context.Context, reader io.Reader, opts ...DeleteOption) error {
+	objs, err := r.decodeYAML(reader)
+	if err != nil {
+		return err
+	}
+
+	for i := len(objs) - 1; i >= 0; i-- {
+		obj := objs[i]
+		if err := r.Delete(ctx, obj, opts...); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// decodeYAML decodes the documents of the YAML manifest read from reader,
+// skipping empty documents.
+func (r *Resources) decodeYAML(reader io.Reader) ([]k8s.Object, error) {
+	var objs []k8s.Object
+	yamlReader := yaml.NewYAMLReader(bufio.NewReader(reader))
+	for {
+		doc, err := yamlReader.Read()
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read yaml document: %w", err)
+		}
+
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(doc, &u.Object); err != nil {
+			return nil, fmt.Errorf("decode yaml document: %w", err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+
+		obj, err := r.typedObject(u)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// typedObject converts u to the type registered in the scheme
+// for its kind, or returns u when the kind is not registered.
+func (r *Resources) typedObject(u *unstructured.Unstructured) (k8s.Object, error) {
+	gvk := u.GroupVersionKind()
+	if !r.scheme.Recognizes(gvk) {
+		return u, nil
+	}
+
+	typed, err := r.scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		return nil, fmt.Errorf("convert %s %s: %w", gvk.Kind, u.GetName(), err)
+	}
+	obj, ok := typed.(k8s.Object)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", typed)
+	}
+	return obj, nil
+}
+
+func (r *Resources) applyObject(ctx context.Context, obj k8s.Object, o *applyOptions) error {
+	if o.namespace != "" {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		mapping, err := r.client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return err
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			obj.SetNamespace(o.namespace)
+		}
+	}
+
+	if !o.createOrUpdate {
+		return r.serverSideApply(ctx, obj)
+	}
+	return r.CreateOrUpdate(ctx, obj)
+}
+
+// CreateOrUpdate creates obj or, when it already exists, replaces the existing
+// object with obj. The resulting object is stored in obj. Unlike Apply, fields
+// of the existing object that are not set in obj are not preserved.
+func (r *Resources) CreateOrUpdate(ctx context.Context, obj k8s.Object) error {
+	// the resource version of an object previously created or updated
+	// must not be set on the object to create
+	resourceVersion := obj.GetResourceVersion()
+	obj.SetResourceVersion("")
+	err := r.client.Create(ctx, obj)
+	if !apierrors.IsAlreadyExists(err) {
+		if err != nil {
+			obj.SetResourceVersion(resourceVersion)
+		}
+		return err
+	}
+
+	existing, ok := obj.DeepCopyObject().(k8s.Object)
+	if !ok {
+		return fmt.Errorf("unexpected object type %T", obj)
+	}
+	if err := r.client.Get(ctx, cr.ObjectKeyFromObject(obj), existing); err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return r.client.Update(ctx, obj)
+}
+
+// ApplyConfigMap creates or updates, using server-side apply, the named
+// ConfigMap with the provided data and returns the applied ConfigMap.
+func (r *Resources) ApplyConfigMap(ctx context.Context, name, namespace string, data map[string]string) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: corev1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       data,
+	}
+	if err := r.serverSideApply(ctx, cm); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// ApplySecret creates or updates, using server-side apply, the named
+// Secret with the provided data and type and returns the applied Secret.
+func (r *Resources) ApplySecret(ctx context.Context, name, namespace string, data map[string][]byte, secretType corev1.SecretType) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: corev1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       data,
+		Type:       secretType,
+	}
+	if err := r.serverSideApply(ctx, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// serverSideApply applies obj, which must have its TypeMeta set,
+// forcing the ownership of conflicting fields.
+func (r *Resources) serverSideApply(ctx context.Context, obj k8s.Object) error {
+	return r.client.Patch(ctx, obj, cr.Apply, cr.ForceOwnership, cr.FieldOwner(fieldManager))
+}
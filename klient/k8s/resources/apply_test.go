@@ -0,0 +1,271 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/deployment-service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: custom\n")...)
+
+	r := &Resources{scheme: scheme.Scheme}
+	objs, err := r.decodeYAML(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("error while decoding yaml", err)
+	}
+	if len(objs) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(objs))
+	}
+
+	if dep, ok := objs[0].(*appsv1.Deployment); !ok || dep.Name != "yaml-test-dep" || dep.Kind != "Deployment" {
+		t.Errorf("unexpected first object %#v", objs[0])
+	}
+	if svc, ok := objs[1].(*corev1.Service); !ok || svc.Name != "yaml-test-svc" {
+		t.Errorf("unexpected second object %#v", objs[1])
+	}
+	if u, ok := objs[2].(*unstructured.Unstructured); !ok || u.GetKind() != "Widget" {
+		t.Errorf("expected an unstructured object for an unregistered kind, got %#v", objs[2])
+	}
+}
+
+func TestCheckKustomizationVersion(t *testing.T) {
+	path, err := kustomizationFile("testdata/kustomize/overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkKustomizationVersion(path, "kustomize.config.k8s.io/v1beta1"); err != nil {
+		t.Error("unexpected error for matching version", err)
+	}
+	if err := checkKustomizationVersion(path, "kustomize.config.k8s.io/v1"); err == nil {
+		t.Error("expected an error for a different version")
+	}
+	if _, err := kustomizationFile("testdata"); err == nil {
+		t.Error("expected an error for a directory without kustomization file")
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []ApplyOption
+		force string
+	}{
+		{name: "without force", force: ""},
+		{name: "with force", opts: []ApplyOption{WithForce(true)}, force: "true"},
+		{name: "force disabled", opts: []ApplyOption{WithForce(false)}, force: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := newDiscoveryMux(t)
+			mux.HandleFunc("/api/v1/namespaces/default/configmaps/test-cm", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPatch {
+					t.Errorf("unexpected method %s", r.Method)
+				}
+				if ct := r.Header.Get("Content-Type"); ct != string(types.ApplyPatchType) {
+					t.Errorf("unexpected content type %s", ct)
+				}
+				if fm := r.URL.Query().Get("fieldManager"); fm != "test-manager" {
+					t.Errorf("unexpected field manager %s", fm)
+				}
+				if force := r.URL.Query().Get("force"); force != test.force {
+					t.Errorf("unexpected force %q, expected %q", force, test.force)
+				}
+
+				var cm corev1.ConfigMap
+				if err := json.NewDecoder(r.Body).Decode(&cm); err != nil {
+					t.Error(err)
+				}
+				if cm.Kind != "ConfigMap" || cm.APIVersion != "v1" {
+					t.Errorf("unexpected type meta %s/%s", cm.APIVersion, cm.Kind)
+				}
+				cm.ResourceVersion = "1"
+				writeJSON(t, w, http.StatusOK, &cm)
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			res, err := New(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cm", Namespace: "default"},
+				Data:       map[string]string{"key": "value"},
+			}
+			if err := res.Apply(context.TODO(), cm, "test-manager", test.opts...); err != nil {
+				t.Fatal(err)
+			}
+			if cm.ResourceVersion != "1" {
+				t.Error("applied object not stored in obj")
+			}
+		})
+	}
+}
+
+func TestCreateAndDeleteFromReader(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+  namespace: default
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: second
+  namespace: default
+`
+
+	var requests []string
+	mux := newDiscoveryMux(t)
+	mux.HandleFunc("/api/v1/namespaces/default/configmaps", func(w http.ResponseWriter, r *http.Request) {
+		var cm corev1.ConfigMap
+		if err := json.NewDecoder(r.Body).Decode(&cm); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, r.Method+" "+cm.Name)
+		writeJSON(t, w, http.StatusCreated, &cm)
+	})
+	mux.HandleFunc("/api/v1/namespaces/default/configmaps/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/default/configmaps/")
+		requests = append(requests, r.Method+" "+name)
+		if name == "second" {
+			writeJSON(t, w, http.StatusNotFound, &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusNotFound, Reason: metav1.StatusReasonNotFound})
+			return
+		}
+		writeJSON(t, w, http.StatusOK, &metav1.Status{Status: metav1.StatusSuccess})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// request JSON bodies to decode the created objects
+	res, err := New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := res.CreateFromReader(context.TODO(), strings.NewReader(manifest)); err != nil {
+		t.Fatal(err)
+	}
+	if err := res.DeleteFromReader(context.TODO(), strings.NewReader(manifest)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"POST first", "POST second", "DELETE second", "DELETE first"}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+
+	if err := res.CreateFromReader(context.TODO(), strings.NewReader("kind: [")); err == nil {
+		t.Error("expected an error for an invalid manifest")
+	}
+}
+
+func TestCreateOrUpdate(t *testing.T) {
+	var stored *corev1.ConfigMap
+	var requests []string
+	mux := newDiscoveryMux(t)
+	mux.HandleFunc("/api/v1/namespaces/default/configmaps", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method)
+		var cm corev1.ConfigMap
+		if err := json.NewDecoder(r.Body).Decode(&cm); err != nil {
+			t.Error(err)
+		}
+		// as the API server, reject the objects to create with a resource version
+		if cm.ResourceVersion != "" {
+			writeJSON(t, w, http.StatusBadRequest, &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+				Message: "resourceVersion should not be set on objects to be created"})
+			return
+		}
+		if stored != nil {
+			writeJSON(t, w, http.StatusConflict, &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusConflict, Reason: metav1.StatusReasonAlreadyExists})
+			return
+		}
+		stored = &cm
+		stored.ResourceVersion = "1"
+		writeJSON(t, w, http.StatusCreated, stored)
+	})
+	mux.HandleFunc("/api/v1/namespaces/default/configmaps/test-cm", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method)
+		if r.Method == http.MethodPut {
+			var cm corev1.ConfigMap
+			if err := json.NewDecoder(r.Body).Decode(&cm); err != nil {
+				t.Error(err)
+			}
+			if cm.ResourceVersion != stored.ResourceVersion {
+				t.Errorf("update with resource version %q, expected %q", cm.ResourceVersion, stored.ResourceVersion)
+			}
+			stored = &cm
+			stored.ResourceVersion = "2"
+		}
+		writeJSON(t, w, http.StatusOK, stored)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// request JSON bodies to decode the created and updated objects
+	res, err := New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same object is created, then updated
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cm", Namespace: "default"},
+		Data:       map[string]string{"key": "value"},
+	}
+	for i, owner := range []string{"first", "second"} {
+		cm.Annotations = map[string]string{"owner": owner}
+		if err := res.CreateOrUpdate(context.TODO(), cm); err != nil {
+			t.Fatalf("call %d: %s", i+1, err)
+		}
+		if cm.Annotations["owner"] != owner || stored.Annotations["owner"] != owner {
+			t.Errorf("call %d: expected annotation owner=%s, got %q and stored %q", i+1, owner, cm.Annotations["owner"], stored.Annotations["owner"])
+		}
+		if expected := fmt.Sprint(i + 1); cm.ResourceVersion != expected {
+			t.Errorf("call %d: expected resource version %s, got %s", i+1, expected, cm.ResourceVersion)
+		}
+	}
+
+	expected := []string{"POST", "POST", "GET", "PUT"}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"io"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var _ io.Closer = &Resources{}
+
+// closers tracks the stop funcs of the background operations, watches and
+// port forwards, started by a Resources value that are still running.
+type closers struct {
+	mu    sync.Mutex
+	next  int
+	funcs map[int]func()
+}
+
+// add tracks stop and returns a func that stops tracking it,
+// to be called once the operation is stopped by its owner.
+func (c *closers) add(stop func()) (remove func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.funcs == nil {
+		c.funcs = make(map[int]func())
+	}
+	id := c.next
+	c.next++
+	c.funcs[id] = stop
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.funcs, id)
+	}
+}
+
+// closeAll calls, and stops tracking, all the tracked stop funcs.
+func (c *closers) closeAll() {
+	c.mu.Lock()
+	funcs := c.funcs
+	c.funcs = nil
+	c.mu.Unlock()
+
+	for _, stop := range funcs {
+		stop()
+	}
+}
+
+// trackedWatch stops tracking a watch when it is stopped
+type trackedWatch struct {
+	watch.Interface
+	remove func()
+}
+
+func (w *trackedWatch) Stop() {
+	w.remove()
+	w.Interface.Stop()
+}
+
+// Close stops the watches (see Watch) and port forwards (see PortForward)
+// started by r that are still running, releasing their goroutines and
+// connections. It is meant to be deferred, i.e. in a teardown step, by
+// tests that do not stop these operations themselves. r can still be
+// used after Close. Close always returns nil.
+func (r *Resources) Close() error {
+	r.closers.closeAll()
+	return nil
+}
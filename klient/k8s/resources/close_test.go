@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestClose(t *testing.T) {
+	mux := newDiscoveryMux(t)
+	mux.HandleFunc("/api/v1/namespaces/default/configmaps", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		// stream no events until the watch is stopped
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}}
+	stopped, err := res.Watch(context.TODO(), cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped.Stop()
+	running, err := res.Watch(context.TODO(), cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.closers.funcs) != 1 {
+		t.Errorf("expected only the running watch to be tracked, got %d operations", len(res.closers.funcs))
+	}
+
+	if err := res.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the watch may report the closed stream with an error event before
+	// closing its result channel
+	timeout := time.After(time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-running.ResultChan():
+			closed = !ok
+		case <-timeout:
+			t.Fatal("watch not stopped by Close")
+		}
+	}
+	if len(res.closers.funcs) != 0 {
+		t.Errorf("expected no tracked operations after Close, got %d", len(res.closers.funcs))
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/yaml"
+)
+
+// diffIgnoredFields are the fields, set by the API server,
+// that are left out of diffs
+var diffIgnoredFields = [][]string{
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "uid"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "selfLink"},
+	{"status"},
+}
+
+// Diff returns, as a unified diff of their YAML serializations, the changes
+// that applying obj would make to the live object, like kubectl diff. The
+// applied object is computed by the API server with a server-side apply dry
+// run, so defaulted fields are not reported as changes. When the object does
+// not exist, all its fields are reported as added. An empty string means
+// there are no changes. Server-managed fields, such as the resource version
+// and the status, are left out.
+func (r *Resources) Diff(ctx context.Context, obj k8s.Object) (string, error) {
+	applied, ok := obj.DeepCopyObject().(k8s.Object)
+	if !ok {
+		return "", fmt.Errorf("diff: unexpected object type %T", obj)
+	}
+	if applied.GetObjectKind().GroupVersionKind().Empty() {
+		gvk, err := apiutil.GVKForObject(applied, r.scheme)
+		if err != nil {
+			return "", fmt.Errorf("diff %s: %w", obj.GetName(), err)
+		}
+		applied.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+
+	// live remains nil when the object does not exist
+	var live runtime.Object
+	liveObj := &unstructured.Unstructured{}
+	liveObj.SetGroupVersionKind(applied.GetObjectKind().GroupVersionKind())
+	err := r.client.Get(ctx, cr.ObjectKeyFromObject(obj), liveObj)
+	switch {
+	case err == nil:
+		live = liveObj
+	case !apierrors.IsNotFound(err):
+		return "", fmt.Errorf("diff %s: get live object: %w", obj.GetName(), err)
+	}
+
+	if err := r.client.Patch(ctx, applied, cr.Apply, cr.DryRunAll, cr.ForceOwnership, cr.FieldOwner(fieldManager)); err != nil {
+		return "", fmt.Errorf("diff %s: dry run apply: %w", obj.GetName(), err)
+	}
+
+	return diffObjects(obj.GetName(), live, applied)
+}
+
+// diffObjects returns the unified diff of the YAML serializations of the live
+// and applied objects, without the ignored fields. A nil live object is empty.
+func diffObjects(name string, live, applied runtime.Object) (string, error) {
+	liveYAML, err := diffYAML(live)
+	if err != nil {
+		return "", fmt.Errorf("diff %s: %w", name, err)
+	}
+	appliedYAML, err := diffYAML(applied)
+	if err != nil {
+		return "", fmt.Errorf("diff %s: %w", name, err)
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(liveYAML),
+		B:        difflib.SplitLines(appliedYAML),
+		FromFile: "live/" + name,
+		ToFile:   "applied/" + name,
+		Context:  3,
+	})
+}
+
+// diffYAML serializes obj to YAML without the ignored fields.
+func diffYAML(obj runtime.Object) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return "", err
+	}
+	for _, field := range diffIgnoredFields {
+		unstructured.RemoveNestedField(content, field...)
+	}
+
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiffObjects(t *testing.T) {
+	newConfigMap := func(value, resourceVersion string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default", ResourceVersion: resourceVersion},
+			Data:       map[string]string{"key": value},
+		}
+	}
+
+	diff, err := diffObjects("cm", newConfigMap("old", "1"), newConfigMap("new", "2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"--- live/cm", "+++ applied/cm", "-  key: old", "+  key: new"} {
+		if !strings.Contains(diff, expected) {
+			t.Errorf("expected diff to contain %q, got:\n%s", expected, diff)
+		}
+	}
+	if strings.Contains(diff, "resourceVersion") {
+		t.Errorf("expected the resource version to be ignored, got:\n%s", diff)
+	}
+
+	diff, err = diffObjects("cm", newConfigMap("same", "1"), newConfigMap("same", "2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Errorf("expected no diff, got:\n%s", diff)
+	}
+
+	diff, err = diffObjects("cm", nil, newConfigMap("new", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "+kind: ConfigMap") {
+		t.Errorf("expected the object to be added, got:\n%s", diff)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/kustomize/api/filesys"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	kustomizetypes "sigs.k8s.io/kustomize/api/types"
+)
+
+// WithKustomizeVersion pins the apiVersion (i.e. kustomize.config.k8s.io/v1beta1)
+// of the kustomization applied by ApplyKustomize. A kustomization declaring
+// another apiVersion is rejected.
+func WithKustomizeVersion(v string) ApplyOption {
+	return func(o *applyOptions) { o.kustomizeVersion = v }
+}
+
+// ApplyKustomize builds the kustomization in directory path, using the kustomize library,
+// and applies the resulting objects (see ApplyYAMLBytes). When path is a plain
+// YAML file, rather than a kustomization directory, the file is applied as is.
+func (r *Resources) ApplyKustomize(ctx context.Context, path string, opts ...ApplyOption) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("apply kustomize: %w", err)
+	}
+	if !info.IsDir() {
+		return r.ApplyYAMLFile(ctx, path, opts...)
+	}
+
+	o := &applyOptions{}
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	kustomization, err := kustomizationFile(path)
+	if err != nil {
+		return fmt.Errorf("apply kustomize: %w", err)
+	}
+	if o.kustomizeVersion != "" {
+		if err := checkKustomizationVersion(kustomization, o.kustomizeVersion); err != nil {
+			return fmt.Errorf("apply kustomize: %w", err)
+		}
+	}
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(filesys.MakeFsOnDisk(), path)
+	if err != nil {
+		return fmt.Errorf("kustomize build %s: %w", path, err)
+	}
+	data, err := resMap.AsYaml()
+	if err != nil {
+		return fmt.Errorf("kustomize build %s: %w", path, err)
+	}
+
+	return r.ApplyYAMLBytes(ctx, data, opts...)
+}
+
+// kustomizationFile returns the path of the kustomization file in dir.
+func kustomizationFile(dir string) (string, error) {
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a kustomize directory: no kustomization file found", dir)
+}
+
+// checkKustomizationVersion returns an error when the apiVersion of the
+// kustomization file is not version. A missing apiVersion is the default one.
+func checkKustomizationVersion(path, version string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var kustomization struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	if err := yaml.Unmarshal(data, &kustomization); err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	if kustomization.APIVersion == "" {
+		kustomization.APIVersion = kustomizetypes.KustomizationVersion
+	}
+	if kustomization.APIVersion != version {
+		return fmt.Errorf("kustomization %s has apiVersion %s, expected %s", path, kustomization.APIVersion, version)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// listPageSize is the maximum number of objects retrieved per list request
+const listPageSize = 500
+
+// ListAcrossNamespaces lists the objects, of the type of objList, from each
+// of the namespaces and stores the merged result, deduplicated by UID, in
+// objList. If namespaces is empty or contains "*", objects are listed
+// across all namespaces. Results are retrieved page by page.
+func (r *Resources) ListAcrossNamespaces(ctx context.Context, namespaces []string, objList k8s.ObjectList) error {
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	for _, ns := range namespaces {
+		if ns == "*" {
+			namespaces = []string{""}
+			break
+		}
+	}
+
+	var items []runtime.Object
+	seen := make(map[types.UID]bool)
+	for _, ns := range namespaces {
+		pageItems, err := r.listAllPages(ctx, ns, objList, &metav1.ListOptions{Limit: listPageSize}, "")
+		if err != nil {
+			return fmt.Errorf("list namespace %q: %w", ns, err)
+		}
+		for _, item := range pageItems {
+			obj, err := meta.Accessor(item)
+			if err != nil {
+				return err
+			}
+			if seen[obj.GetUID()] {
+				continue
+			}
+			seen[obj.GetUID()] = true
+			items = append(items, item)
+		}
+	}
+
+	return meta.SetList(objList, items)
+}
+
+// ListWithPagination lists the objects, of the type of list, page by page
+// retrieving at most pageSize objects per request. Each page is stored in list,
+// replacing the previous page, then passed to fn. Listing stops at the first
+// error returned by fn.
+func (r *Resources) ListWithPagination(ctx context.Context, list k8s.ObjectList, pageSize int64, fn func(k8s.ObjectList) error, opts ...ListOption) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("list with pagination: invalid page size %d", pageSize)
+	}
+
+	listOptions := &metav1.ListOptions{}
+	for _, opt := range opts {
+		opt(listOptions)
+	}
+	listOptions.Limit = pageSize
+
+	continueToken := ""
+	for {
+		if err := r.listPage(ctx, r.namespace, list, listOptions, continueToken); err != nil {
+			return err
+		}
+		continueToken = list.GetContinue()
+		if err := fn(list); err != nil {
+			return err
+		}
+		if continueToken == "" {
+			return nil
+		}
+	}
+}
+
+// GetWithFieldSelector lists, using the dynamic client, the objects of resource
+// gvr in namespace (all namespaces when empty) that match the field selector
+// (i.e. spec.nodeName=worker-1 for pods).
+func (r *Resources) GetWithFieldSelector(ctx context.Context, gvr schema.GroupVersionResource, namespace, fieldSelector string) (*unstructured.UnstructuredList, error) {
+	client, err := dynamic.NewForConfig(r.config)
+	if err != nil {
+		return nil, err
+	}
+	return client.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{FieldSelector: fieldSelector})
+}
+
+// listAllPages retrieves all objects, of the type of objList, from namespace
+// using opts, starting at continueToken and following the continue token of
+// each page.
+func (r *Resources) listAllPages(ctx context.Context, namespace string, objList k8s.ObjectList, opts *metav1.ListOptions, continueToken string) ([]runtime.Object, error) {
+	var items []runtime.Object
+	for {
+		page, ok := objList.DeepCopyObject().(k8s.ObjectList)
+		if !ok {
+			return nil, fmt.Errorf("unexpected list type %T", objList)
+		}
+
+		if err := r.listPage(ctx, namespace, page, opts, continueToken); err != nil {
+			return nil, err
+		}
+
+		pageItems, err := meta.ExtractList(page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, pageItems...)
+
+		continueToken = page.GetContinue()
+		if continueToken == "" {
+			return items, nil
+		}
+	}
+}
+
+// listPage retrieves, in objList, the page of objects starting at continueToken.
+func (r *Resources) listPage(ctx context.Context, namespace string, objList k8s.ObjectList, opts *metav1.ListOptions, continueToken string) error {
+	raw := opts.DeepCopy()
+	// Limit and Continue must be set on the controller-runtime
+	// options, otherwise they override the values set in Raw.
+	o := &cr.ListOptions{Namespace: namespace, Limit: raw.Limit, Continue: continueToken, Raw: raw}
+	// the response omits an empty continue token, clear any previous value
+	objList.SetContinue("")
+	if err := r.client.List(ctx, objList, o); err != nil {
+		return err
+	}
+
+	klog.V(6).Infof("listed page of %T in namespace %q: %d items, continue: %t",
+		objList, namespace, meta.LenList(objList), objList.GetContinue() != "")
+	return nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// newPagingServer returns an API server that serves the configmaps of
+// namespace default in pages of pageSize items, or of the requested
+// limit when set, out of total.
+func newPagingServer(t *testing.T, total, pageSize int) *httptest.Server {
+	mux := newDiscoveryMux(t)
+	mux.HandleFunc("/api/v1/namespaces/default/configmaps", func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("continue"))
+		end := start + pageSize
+		if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+			end = start + limit
+		}
+		if end > total {
+			end = total
+		}
+
+		list := &corev1.ConfigMapList{TypeMeta: metav1.TypeMeta{Kind: "ConfigMapList", APIVersion: "v1"}}
+		for i := start; i < end; i++ {
+			list.Items = append(list.Items, corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: "default"},
+			})
+		}
+		if end < total {
+			list.Continue = strconv.Itoa(end)
+		}
+		writeJSON(t, w, http.StatusOK, list)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestListPagination(t *testing.T) {
+	server := newPagingServer(t, 25, 10)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := res.WithNamespace("default").List(context.TODO(), &cms); err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	if len(cms.Items) != 25 {
+		t.Errorf("expected 25 configmaps across pages, got %d", len(cms.Items))
+	}
+	if cms.Continue != "" {
+		t.Errorf("expected an empty continue token, got %q", cms.Continue)
+	}
+}
+
+func TestInNamespace(t *testing.T) {
+	server := newPagingServer(t, 3, 10)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var list corev1.ConfigMapList
+	if err := res.InNamespace("default").List(context.TODO(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 3 {
+		t.Errorf("expected 3 configmaps in namespace default, got %d", len(list.Items))
+	}
+
+	// res still lists across all namespaces, which the server does not serve
+	if err := res.List(context.TODO(), &corev1.ConfigMapList{}); err == nil {
+		t.Error("expected the namespace of the original Resources to be unchanged")
+	}
+}
+
+func TestListWithPageSize(t *testing.T) {
+	server := newPagingServer(t, 200, 200)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := res.WithNamespace("default").List(context.TODO(), &cms, WithPageSize(50)); err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	if len(cms.Items) != 200 {
+		t.Errorf("expected 200 configmaps across pages, got %d", len(cms.Items))
+	}
+}
+
+func TestListWithPagination(t *testing.T) {
+	server := newPagingServer(t, 200, 200)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pages, total int
+	seen := make(map[string]bool)
+	err = res.WithNamespace("default").ListWithPagination(context.TODO(), &corev1.ConfigMapList{}, 50, func(list k8s.ObjectList) error {
+		cms := list.(*corev1.ConfigMapList)
+		if len(cms.Items) != 50 {
+			t.Errorf("expected pages of 50 configmaps, got %d", len(cms.Items))
+		}
+		for _, cm := range cms.Items {
+			seen[cm.Name] = true
+		}
+		pages++
+		total += len(cms.Items)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	if pages != 4 {
+		t.Errorf("expected 4 pages, got %d", pages)
+	}
+	if total != 200 || len(seen) != 200 {
+		t.Errorf("expected 200 distinct configmaps, got %d (%d distinct)", total, len(seen))
+	}
+
+	stop := errors.New("stop")
+	pages = 0
+	err = res.WithNamespace("default").ListWithPagination(context.TODO(), &corev1.ConfigMapList{}, 50, func(k8s.ObjectList) error {
+		pages++
+		return stop
+	})
+	if !errors.Is(err, stop) || pages != 1 {
+		t.Errorf("expected listing to stop on the first error, got %v after %d pages", err, pages)
+	}
+}
+
+func TestGetWithFieldSelector(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/namespaces/default/pods", func(w http.ResponseWriter, r *http.Request) {
+		pods := map[string]string{"pod-a": "worker-1", "pod-b": "worker-2", "pod-c": "worker-1"}
+		list := &corev1.PodList{TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"}}
+		for _, name := range []string{"pod-a", "pod-b", "pod-c"} {
+			if r.URL.Query().Get("fieldSelector") != "spec.nodeName="+pods[name] {
+				continue
+			}
+			list.Items = append(list.Items, corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       corev1.PodSpec{NodeName: pods[name]},
+			})
+		}
+		writeJSON(t, w, http.StatusOK, list)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gvr := corev1.SchemeGroupVersion.WithResource("pods")
+	pods, err := res.GetWithFieldSelector(context.TODO(), gvr, "default", "spec.nodeName=worker-1")
+	if err != nil {
+		t.Fatal("error while listing pods", err)
+	}
+	if len(pods.Items) != 2 {
+		t.Fatalf("expected 2 pods on worker-1, got %d", len(pods.Items))
+	}
+	for _, pod := range pods.Items {
+		if node, _, _ := unstructured.NestedString(pod.Object, "spec", "nodeName"); node != "worker-1" {
+			t.Errorf("pod %s is on node %s", pod.GetName(), node)
+		}
+	}
+}
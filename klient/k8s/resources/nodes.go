@@ -0,0 +1,252 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultDrainTimeout  = 5 * time.Minute
+	drainPollingInterval = 2 * time.Second
+	// evictionRetryInterval is the initial interval between the eviction
+	// attempts of a pod protected by a PodDisruptionBudget
+	evictionRetryInterval    = 500 * time.Millisecond
+	maxEvictionRetryInterval = 10 * time.Second
+	// evictionSubresource is the subresource of pods used to evict them
+	evictionSubresource = "pods/eviction"
+	// policyv1GroupVersion is the group version of the Eviction API
+	// served since Kubernetes 1.22
+	policyv1GroupVersion = "policy/v1"
+)
+
+type drainOptions struct {
+	timeout time.Duration
+}
+
+// DrainOption is used to provide additional arguments to the Drain call.
+type DrainOption func(*drainOptions)
+
+// WithDrainTimeout sets the maximum amount of time Drain waits for
+// the evicted pods to be removed from the node.
+func WithDrainTimeout(d time.Duration) DrainOption {
+	return func(do *drainOptions) { do.timeout = d }
+}
+
+// GetNodesByLabel returns the nodes that have all the labels
+// (i.e. node.kubernetes.io/instance-type=g4dn.xlarge).
+func (r *Resources) GetNodesByLabel(ctx context.Context, labels map[string]string) (*corev1.NodeList, error) {
+	nodes := &corev1.NodeList{}
+	selector := k8slabels.SelectorFromSet(labels).String()
+	if err := r.List(ctx, nodes, WithLabelSelector(selector)); err != nil {
+		return nil, fmt.Errorf("get nodes by label %q: %w", selector, err)
+	}
+	return nodes, nil
+}
+
+// GetNodeByName returns the named node.
+func (r *Resources) GetNodeByName(ctx context.Context, name string) (*corev1.Node, error) {
+	node := &corev1.Node{}
+	if err := r.Get(ctx, name, "", node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// Cordon marks the node as unschedulable.
+func (r *Resources) Cordon(ctx context.Context, node *corev1.Node) error {
+	return r.setUnschedulable(ctx, node, true)
+}
+
+// Uncordon marks the node as schedulable.
+func (r *Resources) Uncordon(ctx context.Context, node *corev1.Node) error {
+	return r.setUnschedulable(ctx, node, false)
+}
+
+// Drain cordons the node then evicts all of its pods, except for pods
+// managed by a DaemonSet, static (mirror) pods and pods that already
+// terminated. Pods are evicted through the policy/v1 Eviction API, or
+// policy/v1beta1 on API servers that do not serve it. It returns once the
+// evicted pods are gone or the drain timeout (see WithDrainTimeout) expires.
+// Evictions denied by a PodDisruptionBudget (429 Too Many Requests) are
+// retried, with an exponential backoff, until the drain timeout expires.
+func (r *Resources) Drain(ctx context.Context, node *corev1.Node, opts ...DrainOption) error {
+	drainOpts := &drainOptions{timeout: defaultDrainTimeout}
+	for _, fn := range opts {
+		fn(drainOpts)
+	}
+
+	if err := r.Cordon(ctx, node); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, drainOpts.timeout)
+	defer cancel()
+
+	pods, err := r.evictablePods(ctx, node)
+	if err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return fmt.Errorf("drain node %s: %w", node.Name, err)
+	}
+
+	groupVersion, err := evictionGroupVersion(clientset)
+	if err != nil {
+		return fmt.Errorf("drain node %s: %w", node.Name, err)
+	}
+
+	for i := range pods {
+		if err := evictPod(ctx, clientset, groupVersion, &pods[i]); err != nil {
+			return fmt.Errorf("drain node %s: evict pod %s/%s: %w", node.Name, pods[i].Namespace, pods[i].Name, err)
+		}
+	}
+
+	err = wait.PollImmediateUntil(drainPollingInterval, func() (bool, error) {
+		remaining, err := r.evictablePods(ctx, node)
+		if err != nil {
+			return false, err
+		}
+		return len(remaining) == 0, nil
+	}, ctx.Done())
+	if err != nil {
+		return fmt.Errorf("drain node %s: %w", node.Name, err)
+	}
+
+	return nil
+}
+
+// evictionGroupVersion returns the group version of the Eviction API served
+// by the API server: policy/v1, unless discovery only reports policy/v1beta1
+// (i.e. Kubernetes < 1.22, which does not serve policy/v1 evictions).
+func evictionGroupVersion(clientset kubernetes.Interface) (string, error) {
+	resources, err := clientset.Discovery().ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		return "", fmt.Errorf("discover eviction support: %w", err)
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == evictionSubresource && resource.Kind == "Eviction" &&
+			resource.Group == policyv1beta1.GroupName && resource.Version == policyv1beta1.SchemeGroupVersion.Version {
+			return policyv1beta1.SchemeGroupVersion.String(), nil
+		}
+	}
+	return policyv1GroupVersion, nil
+}
+
+// evictPod evicts the pod using the Eviction API of groupVersion, retrying
+// while the eviction is denied by a PodDisruptionBudget until ctx is done.
+func evictPod(ctx context.Context, clientset kubernetes.Interface, groupVersion string, pod *corev1.Pod) error {
+	// policy/v1 Eviction has the same schema as policy/v1beta1 but is not
+	// part of the k8s.io/api version in use, so the body is encoded here.
+	data, err := json.Marshal(&policyv1beta1.Eviction{
+		TypeMeta:   metav1.TypeMeta{Kind: "Eviction", APIVersion: groupVersion},
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	})
+	if err != nil {
+		return err
+	}
+
+	backoff := wait.Backoff{Duration: evictionRetryInterval, Factor: 2, Steps: 10, Cap: maxEvictionRetryInterval}
+	for {
+		err := clientset.CoreV1().RESTClient().Post().
+			Namespace(pod.Namespace).
+			Resource("pods").
+			Name(pod.Name).
+			SubResource("eviction").
+			SetHeader("Content-Type", runtime.ContentTypeJSON).
+			Body(data).
+			Do(ctx).
+			Error()
+		if err == nil || apierrors.IsNotFound(err) {
+			return nil
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s", ctx.Err(), err)
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+func (r *Resources) setUnschedulable(ctx context.Context, node *corev1.Node, unschedulable bool) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"unschedulable": unschedulable,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.client.Patch(ctx, node, cr.RawPatch(types.MergePatchType, data))
+}
+
+// evictablePods returns the pods scheduled on node that are neither
+// managed by a DaemonSet, static pods nor terminated (Succeeded or Failed).
+func (r *Resources) evictablePods(ctx context.Context, node *corev1.Node) ([]corev1.Pod, error) {
+	var pods corev1.PodList
+	listOptions := &metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.Name).String()}
+	if err := r.client.List(ctx, &pods, &cr.ListOptions{Raw: listOptions}); err != nil {
+		return nil, err
+	}
+
+	var result []corev1.Pod
+	for _, pod := range pods.Items {
+		if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+			continue
+		}
+		if isDaemonSetPod(&pod) {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		result = append(result, pod)
+	}
+
+	return result, nil
+}
+
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// newDrainServer returns an API server with node `test-node` running pod
+// `test-pod`, whose first n evictions are denied by a PodDisruptionBudget,
+// along with the terminated pods `succeeded-pod` and `failed-pod`, which must
+// not be evicted. Evictions are served by the policy/<evictionVersion> API.
+// The number of eviction requests is returned by evictions.
+func newDrainServer(t *testing.T, n int, evictionVersion string) (server *httptest.Server, evictions func() int) {
+	var (
+		mu      sync.Mutex
+		calls   int
+		evicted bool
+	)
+	node := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{Kind: "Node", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, w, http.StatusOK, &metav1.APIVersions{Versions: []string{"v1"}})
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, w, http.StatusOK, &metav1.APIGroupList{})
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, w, http.StatusOK, &metav1.APIResourceList{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "nodes", Kind: "Node", Verbs: metav1.Verbs{"get", "list", "patch"}},
+				{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"get", "list"}},
+				{Name: "pods/eviction", Namespaced: true, Group: "policy", Version: evictionVersion, Kind: "Eviction", Verbs: metav1.Verbs{"create"}},
+			},
+		})
+	})
+	mux.HandleFunc("/api/v1/nodes/test-node", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, w, http.StatusOK, node)
+	})
+	mux.HandleFunc("/api/v1/pods", func(w http.ResponseWriter, r *http.Request) {
+		if selector := r.URL.Query().Get("fieldSelector"); selector != "spec.nodeName=test-node" {
+			t.Errorf("unexpected field selector %q", selector)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		pods := &corev1.PodList{
+			TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"},
+			Items: []corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "succeeded-pod", Namespace: "default"}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "failed-pod", Namespace: "default"}, Status: corev1.PodStatus{Phase: corev1.PodFailed}},
+			},
+		}
+		if !evicted {
+			pods.Items = append(pods.Items, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}})
+		}
+		writeJSON(t, w, http.StatusOK, pods)
+	})
+	for _, name := range []string{"succeeded-pod", "failed-pod"} {
+		name := name
+		mux.HandleFunc("/api/v1/namespaces/default/pods/"+name+"/eviction", func(w http.ResponseWriter, _ *http.Request) {
+			t.Errorf("unexpected eviction of terminated pod %s", name)
+			writeJSON(t, w, http.StatusCreated, &metav1.Status{Status: metav1.StatusSuccess})
+		})
+	}
+	mux.HandleFunc("/api/v1/namespaces/default/pods/test-pod/eviction", func(w http.ResponseWriter, r *http.Request) {
+		var eviction metav1.TypeMeta
+		if err := json.NewDecoder(r.Body).Decode(&eviction); err != nil {
+			t.Errorf("failed to decode eviction: %s", err)
+		}
+		if eviction.APIVersion != "policy/"+evictionVersion || eviction.Kind != "Eviction" {
+			t.Errorf("expected a policy/%s Eviction, got %s %s", evictionVersion, eviction.APIVersion, eviction.Kind)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= n {
+			writeJSON(t, w, http.StatusTooManyRequests, &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusTooManyRequests,
+				Reason:  metav1.StatusReasonTooManyRequests,
+				Message: "Cannot evict pod as it would violate the pod's disruption budget.",
+			})
+			return
+		}
+		evicted = true
+		writeJSON(t, w, http.StatusCreated, &metav1.Status{Status: metav1.StatusSuccess})
+	})
+
+	return httptest.NewServer(mux), func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestDrainRetriesDisruptionBudgetEvictions(t *testing.T) {
+	server, evictions := newDrainServer(t, 2, "v1")
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}
+	if err := res.Drain(context.TODO(), node, WithDrainTimeout(time.Minute)); err != nil {
+		t.Fatal("error while draining node", err)
+	}
+	if evictions() != 3 {
+		t.Errorf("expected 3 eviction requests, got %d", evictions())
+	}
+}
+
+func TestDrainTimeoutOnDisruptionBudget(t *testing.T) {
+	server, evictions := newDrainServer(t, 1000, "v1")
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}
+	err = res.Drain(context.TODO(), node, WithDrainTimeout(time.Second))
+	if err == nil {
+		t.Fatal("expected the drain to time out")
+	}
+	if !strings.Contains(err.Error(), "too many requests") {
+		t.Errorf("expected the eviction error to be reported, got %s", err)
+	}
+	if evictions() < 2 {
+		t.Errorf("expected the eviction to be retried, got %d requests", evictions())
+	}
+}
+
+func TestDrainEvictionAPIVersion(t *testing.T) {
+	for _, version := range []string{"v1", "v1beta1"} {
+		t.Run(version, func(t *testing.T) {
+			server, evictions := newDrainServer(t, 0, version)
+			defer server.Close()
+
+			res, err := New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}
+			if err := res.Drain(context.TODO(), node, WithDrainTimeout(time.Minute)); err != nil {
+				t.Fatal("error while draining node", err)
+			}
+			if evictions() != 1 {
+				t.Errorf("expected 1 eviction request, got %d", evictions())
+			}
+		})
+	}
+}
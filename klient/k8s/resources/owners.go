@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// UpdateOwnerReferences sets the owner references of obj to the provided
+// owners. The APIVersion and Kind of each owner are resolved from the scheme.
+// Namespaced owners must be in the same namespace as obj and cluster-scoped
+// objects cannot be owned by namespaced objects.
+func (r *Resources) UpdateOwnerReferences(ctx context.Context, obj k8s.Object, owners []k8s.Object) error {
+	refs := make([]metav1.OwnerReference, 0, len(owners))
+	for _, owner := range owners {
+		if owner.GetNamespace() != "" && owner.GetNamespace() != obj.GetNamespace() {
+			return fmt.Errorf("owner %s/%s: owner namespace must match namespace %q of %s",
+				owner.GetNamespace(), owner.GetName(), obj.GetNamespace(), obj.GetName())
+		}
+
+		gvk, err := apiutil.GVKForObject(owner, r.scheme)
+		if err != nil {
+			return fmt.Errorf("owner %s: %w", owner.GetName(), err)
+		}
+
+		refs = append(refs, metav1.OwnerReference{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Name:       owner.GetName(),
+			UID:        owner.GetUID(),
+		})
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"ownerReferences": refs,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.client.Patch(ctx, obj, cr.RawPatch(types.MergePatchType, data))
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type getPodOptions struct {
+	allowMultiple bool
+}
+
+// GetPodOption is used to provide additional arguments to the GetPodByLabel call.
+type GetPodOption func(*getPodOptions)
+
+// WithAllowMultiple makes GetPodByLabel return the first matching
+// pod, instead of an error, when several pods match the labels.
+func WithAllowMultiple() GetPodOption {
+	return func(o *getPodOptions) { o.allowMultiple = true }
+}
+
+type execOptions struct {
+	stdin io.Reader
+}
+
+// ExecOption is used to provide additional arguments to the ExecInPod call.
+type ExecOption func(*execOptions)
+
+// WithStdin sets the reader streamed to the standard input of the command.
+func WithStdin(r io.Reader) ExecOption {
+	return func(o *execOptions) { o.stdin = r }
+}
+
+// ReadinessGateStatus fetches the pod and returns the status of its condition
+// of type conditionType, which is typically used as a pod readiness gate.
+// ConditionUnknown is returned, without error, if the condition is not yet set.
+func (r *Resources) ReadinessGateStatus(ctx context.Context, pod *corev1.Pod, conditionType corev1.PodConditionType) (corev1.ConditionStatus, error) {
+	if err := r.Get(ctx, pod.GetName(), pod.GetNamespace(), pod); err != nil {
+		return corev1.ConditionUnknown, err
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == conditionType {
+			return cond.Status, nil
+		}
+	}
+
+	return corev1.ConditionUnknown, nil
+}
+
+// GetPodsByLabel returns the pods of the namespace that have all the labels.
+func (r *Resources) GetPodsByLabel(ctx context.Context, namespace string, labels map[string]string) (*corev1.PodList, error) {
+	pods := &corev1.PodList{}
+	listOptions := &metav1.ListOptions{LabelSelector: k8slabels.SelectorFromSet(labels).String()}
+	if err := r.client.List(ctx, pods, &cr.ListOptions{Namespace: namespace, Raw: listOptions}); err != nil {
+		return nil, fmt.Errorf("get pods by label %q: %w", listOptions.LabelSelector, err)
+	}
+	return pods, nil
+}
+
+// GetPodByLabel returns the pod of the namespace that has all the labels
+// (i.e. the pod of a controller). An error is returned when no pod, or more
+// than one pod unless WithAllowMultiple is set, matches the labels.
+func (r *Resources) GetPodByLabel(ctx context.Context, namespace string, labels map[string]string, opts ...GetPodOption) (*corev1.Pod, error) {
+	o := &getPodOptions{}
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	pods, err := r.GetPodsByLabel(ctx, namespace, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := k8slabels.SelectorFromSet(labels).String()
+	switch {
+	case len(pods.Items) == 0:
+		return nil, fmt.Errorf("get pod by label %q: no pod found in namespace %q", selector, namespace)
+	case len(pods.Items) > 1 && !o.allowMultiple:
+		return nil, fmt.Errorf("get pod by label %q: %d pods found in namespace %q", selector, len(pods.Items), namespace)
+	}
+	return &pods.Items[0], nil
+}
+
+// GetLogs returns a stream of the logs of the pod, retrieved from the
+// pods/log subresource with opts (i.e. the container or Follow), which
+// may be nil. The caller must close the stream.
+func (r *Resources) GetLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	if opts == nil {
+		opts = &corev1.PodLogOptions{}
+	}
+
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return nil, fmt.Errorf("get logs of pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+
+	stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get logs of pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	return stream, nil
+}
+
+// GetLogsAsString returns the logs of the pod (see GetLogs).
+func (r *Resources) GetLogsAsString(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (string, error) {
+	stream, err := r.GetLogs(ctx, pod, opts)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	logs, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("read logs of pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	return string(logs), nil
+}
+
+// StreamLogs copies the logs of the pod (see GetLogs) to w until the log
+// stream ends or ctx is done, which is not reported as an error. Set
+// opts.Follow to keep streaming the logs as they are written.
+func (r *Resources) StreamLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions, w io.Writer) error {
+	stream, err := r.GetLogs(ctx, pod, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if _, err := io.Copy(w, stream); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("stream logs of pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	return nil
+}
+
+// ExecInPod runs the command in the container of the pod, like kubectl exec,
+// and returns its standard output and error. An error is returned when the
+// command cannot be run or exits with a non-zero status. Since the command
+// stream cannot be interrupted, ExecInPod returns when ctx is done without
+// waiting for the command to complete.
+func (r *Resources) ExecInPod(ctx context.Context, namespace, podName, containerName string, command []string, opts ...ExecOption) (stdout, stderr string, err error) {
+	o := &execOptions{}
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return "", "", fmt.Errorf("exec in pod %s/%s: %w", namespace, podName, err)
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   command,
+			Stdin:     o.stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(r.config, "POST", req.URL())
+	if err != nil {
+		return "", "", fmt.Errorf("exec in pod %s/%s: %w", namespace, podName, err)
+	}
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- executor.Stream(remotecommand.StreamOptions{Stdin: o.stdin, Stdout: &stdoutBuf, Stderr: &stderrBuf})
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return "", "", fmt.Errorf("exec in pod %s/%s: %w", namespace, podName, ctx.Err())
+	}
+	if err != nil {
+		return stdoutBuf.String(), stderrBuf.String(), fmt.Errorf("exec in pod %s/%s: %w", namespace, podName, err)
+	}
+	return stdoutBuf.String(), stderrBuf.String(), nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// newLogServer returns an API server that serves the logs, `hello`, of pod
+// default/echo. When following the logs, the stream is kept open after the
+// logs are written, until the request is cancelled.
+func newLogServer(t *testing.T) *httptest.Server {
+	mux := newDiscoveryMux(t)
+	mux.HandleFunc("/api/v1/namespaces/default/pods/echo/log", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if _, err := w.Write([]byte("hello\n")); err != nil {
+			t.Error(err)
+		}
+		if r.URL.Query().Get("follow") != "true" {
+			return
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestGetLogs(t *testing.T) {
+	server := newLogServer(t)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "echo", Namespace: "default"}}
+
+	logs, err := res.GetLogsAsString(context.TODO(), pod, nil)
+	if err != nil {
+		t.Fatal("error while getting logs", err)
+	}
+	if logs != "hello\n" {
+		t.Errorf("unexpected logs %q", logs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	var buf bytes.Buffer
+	if err := res.StreamLogs(ctx, pod, &corev1.PodLogOptions{Follow: true}, &buf); err != nil {
+		t.Fatal("error while streaming logs", err)
+	}
+	if buf.String() != "hello\n" {
+		t.Errorf("unexpected streamed logs %q", buf.String())
+	}
+}
+
+func TestGetPodByLabel(t *testing.T) {
+	mux := newDiscoveryMux(t)
+	mux.HandleFunc("/api/v1/namespaces/default/pods", func(w http.ResponseWriter, r *http.Request) {
+		pods := map[string]string{"controller-0": "controller", "worker-0": "worker", "worker-1": "worker"}
+		list := &corev1.PodList{TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"}}
+		for _, name := range []string{"controller-0", "worker-0", "worker-1"} {
+			if r.URL.Query().Get("labelSelector") != "app="+pods[name] {
+				continue
+			}
+			list.Items = append(list.Items, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}})
+		}
+		writeJSON(t, w, http.StatusOK, list)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		app        string
+		opts       []GetPodOption
+		expected   string
+		shouldFail bool
+	}{
+		{name: "single match", app: "controller", expected: "controller-0"},
+		{name: "no match", app: "missing", shouldFail: true},
+		{name: "multiple matches", app: "worker", shouldFail: true},
+		{name: "multiple matches allowed", app: "worker", opts: []GetPodOption{WithAllowMultiple()}, expected: "worker-0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod, err := res.GetPodByLabel(context.TODO(), "default", map[string]string{"app": test.app}, test.opts...)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pod.Name != test.expected {
+				t.Errorf("expected pod %s, got %s", test.expected, pod.Name)
+			}
+		})
+	}
+
+	pods, err := res.GetPodsByLabel(context.TODO(), "default", map[string]string{"app": "worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods.Items) != 2 {
+		t.Errorf("expected 2 worker pods, got %d", len(pods.Items))
+	}
+}
+
+func TestReadinessGateStatus(t *testing.T) {
+	const gate corev1.PodConditionType = "example.com/load-balancer-ready"
+	mux := newDiscoveryMux(t)
+	pod := func(name string, conditions ...corev1.PodCondition) {
+		mux.HandleFunc("/api/v1/namespaces/default/pods/"+name, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(t, w, http.StatusOK, &corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Status:     corev1.PodStatus{Conditions: conditions},
+			})
+		})
+	}
+	ready := corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue}
+	pod("pending")
+	pod("gate-unset", ready)
+	pod("gate-false", ready, corev1.PodCondition{Type: gate, Status: corev1.ConditionFalse})
+	pod("gate-true", ready, corev1.PodCondition{Type: gate, Status: corev1.ConditionTrue})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pod        string
+		expected   corev1.ConditionStatus
+		shouldFail bool
+	}{
+		{pod: "pending", expected: corev1.ConditionUnknown},
+		{pod: "gate-unset", expected: corev1.ConditionUnknown},
+		{pod: "gate-false", expected: corev1.ConditionFalse},
+		{pod: "gate-true", expected: corev1.ConditionTrue},
+		{pod: "missing", expected: corev1.ConditionUnknown, shouldFail: true},
+	}
+	for _, test := range tests {
+		t.Run(test.pod, func(t *testing.T) {
+			status, err := res.ReadinessGateStatus(context.TODO(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: test.pod, Namespace: "default"}}, gate)
+			if test.shouldFail != (err != nil) {
+				t.Fatalf("expected error %t, got %v", test.shouldFail, err)
+			}
+			if status != test.expected {
+				t.Errorf("expected status %s, got %s", test.expected, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForward forwards localPort, on localhost, to remotePort of the pod, like
+// kubectl port-forward, and returns once the tunnel is established. The tunnel
+// is torn down when the returned cancel func is called, ctx is done or the
+// Resources are closed (see Close).
+func (r *Resources) PortForward(ctx context.Context, namespace, podName string, localPort, remotePort int) (cancel context.CancelFunc, err error) {
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return nil, fmt.Errorf("port forward %s/%s: %w", namespace, podName, err)
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(r.config)
+	if err != nil {
+		return nil, fmt.Errorf("port forward %s/%s: %w", namespace, podName, err)
+	}
+	url := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	stopCh, readyCh := make(chan struct{}), make(chan struct{})
+	ports := []string{fmt.Sprintf("%d:%d", localPort, remotePort)}
+	forwarder, err := portforward.New(dialer, ports, stopCh, readyCh, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("port forward %s/%s: %w", namespace, podName, err)
+	}
+
+	var once sync.Once
+	var remove func()
+	cancel = func() {
+		once.Do(func() {
+			remove()
+			close(stopCh)
+		})
+	}
+	remove = r.closers.add(cancel)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- forwarder.ForwardPorts() }()
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		cancel()
+		return nil, fmt.Errorf("port forward %s/%s: %w", namespace, podName, err)
+	case <-ctx.Done():
+		cancel()
+		return nil, fmt.Errorf("port forward %s/%s: %w", namespace, podName, ctx.Err())
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-stopCh:
+		}
+	}()
+	return cancel, nil
+}
+
+// PortForwardService forwards localPort, on localhost, to the target port of
+// the first port of the service, on a running pod selected by the service
+// (see PortForward).
+func (r *Resources) PortForwardService(ctx context.Context, svc *corev1.Service, localPort int) (cancel context.CancelFunc, err error) {
+	if len(svc.Spec.Selector) == 0 || len(svc.Spec.Ports) == 0 {
+		return nil, fmt.Errorf("port forward service %s/%s: service has no selector or ports", svc.Namespace, svc.Name)
+	}
+
+	pod, err := r.runningPodForSelector(ctx, svc.Namespace, svc.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("port forward service %s/%s: %w", svc.Namespace, svc.Name, err)
+	}
+
+	remotePort, err := targetPort(pod, svc.Spec.Ports[0])
+	if err != nil {
+		return nil, fmt.Errorf("port forward service %s/%s: %w", svc.Namespace, svc.Name, err)
+	}
+	return r.PortForward(ctx, pod.Namespace, pod.Name, localPort, remotePort)
+}
+
+// runningPodForSelector returns a running pod of the namespace matching selector.
+func (r *Resources) runningPodForSelector(ctx context.Context, namespace string, selector map[string]string) (*corev1.Pod, error) {
+	pods, err := r.GetPodsByLabel(ctx, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodRunning && pods.Items[i].DeletionTimestamp == nil {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no running pod matches selector %s", labels.SelectorFromSet(selector))
+}
+
+// targetPort returns the pod port the service port targets, resolving
+// named target ports using the ports of the pod's containers.
+func targetPort(pod *corev1.Pod, port corev1.ServicePort) (int, error) {
+	switch {
+	case port.TargetPort.Type == intstr.String && port.TargetPort.StrVal != "":
+		for _, container := range pod.Spec.Containers {
+			for _, containerPort := range container.Ports {
+				if containerPort.Name == port.TargetPort.StrVal {
+					return int(containerPort.ContainerPort), nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("pod %s has no port named %s", pod.Name, port.TargetPort.StrVal)
+	case port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal != 0:
+		return int(port.TargetPort.IntVal), nil
+	default:
+		// the target port defaults to the service port
+		return int(port.Port), nil
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestTargetPort(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "nginx", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+	}}}
+
+	tests := []struct {
+		name       string
+		port       corev1.ServicePort
+		expected   int
+		shouldFail bool
+	}{
+		{name: "numeric target port", port: corev1.ServicePort{Port: 80, TargetPort: intstr.FromInt(8081)}, expected: 8081},
+		{name: "named target port", port: corev1.ServicePort{Port: 80, TargetPort: intstr.FromString("http")}, expected: 8080},
+		{name: "default target port", port: corev1.ServicePort{Port: 80}, expected: 80},
+		{name: "unknown named port", port: corev1.ServicePort{Port: 80, TargetPort: intstr.FromString("grpc")}, shouldFail: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			port, err := targetPort(pod, test.port)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if port != test.expected {
+				t.Errorf("expected port %d, got %d", test.expected, port)
+			}
+		})
+	}
+}
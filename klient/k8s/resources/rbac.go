@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CreateServiceAccount creates a service account with the provided
+// annotations (i.e. eks.amazonaws.com/role-arn) and returns it.
+func (r *Resources) CreateServiceAccount(ctx context.Context, name, namespace string, annotations map[string]string) (*corev1.ServiceAccount, error) {
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+	}
+	if err := r.Create(ctx, sa); err != nil {
+		return nil, err
+	}
+	return sa, nil
+}
+
+// BindClusterRole binds the service account to the named cluster role using a
+// ClusterRoleBinding named after the namespace and name of the service account
+// and the role, i.e. <namespace>-<service account>-<role>, as ClusterRoleBindings
+// are cluster-scoped.
+func (r *Resources) BindClusterRole(ctx context.Context, sa *corev1.ServiceAccount, clusterRoleName string) error {
+	_, err := r.CreateClusterRoleBinding(ctx, clusterBindingName(sa, clusterRoleName), clusterRoleName, []rbacv1.Subject{serviceAccountSubject(sa)})
+	return err
+}
+
+// BindRole binds the service account to the named role, in namespace,
+// using a RoleBinding named after both the service account and the role.
+func (r *Resources) BindRole(ctx context.Context, sa *corev1.ServiceAccount, roleName, namespace string) error {
+	_, err := r.CreateRoleBinding(ctx, bindingName(sa, roleName), namespace, roleName, []rbacv1.Subject{serviceAccountSubject(sa)})
+	return err
+}
+
+// CreateRoleBinding creates a RoleBinding, in namespace, that grants
+// the named role to the subjects and returns it.
+func (r *Resources) CreateRoleBinding(ctx context.Context, name, namespace, roleName string, subjects []rbacv1.Subject) (*rbacv1.RoleBinding, error) {
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: roleName},
+		Subjects:   subjects,
+	}
+	if err := r.Create(ctx, binding); err != nil {
+		return nil, err
+	}
+	return binding, nil
+}
+
+// CreateClusterRoleBinding creates a ClusterRoleBinding that grants
+// the named cluster role to the subjects and returns it.
+func (r *Resources) CreateClusterRoleBinding(ctx context.Context, name, clusterRoleName string, subjects []rbacv1.Subject) (*rbacv1.ClusterRoleBinding, error) {
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRoleName},
+		Subjects:   subjects,
+	}
+	if err := r.Create(ctx, binding); err != nil {
+		return nil, err
+	}
+	return binding, nil
+}
+
+// GetServiceAccountToken requests a token for the named service account,
+// in namespace, bound to the audiences, using the TokenRequest API. The token
+// expires after expirationSeconds, or the API server default when zero.
+func (r *Resources) GetServiceAccountToken(ctx context.Context, saName, namespace string, audiences []string, expirationSeconds int64) (string, error) {
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return "", fmt.Errorf("get token of service account %s/%s: %w", namespace, saName, err)
+	}
+
+	request := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{Audiences: audiences},
+	}
+	if expirationSeconds > 0 {
+		request.Spec.ExpirationSeconds = &expirationSeconds
+	}
+	request, err = clientset.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, saName, request, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("get token of service account %s/%s: %w", namespace, saName, err)
+	}
+	return request.Status.Token, nil
+}
+
+// VerifyToken reviews the token using the TokenReview API and returns
+// whether it is authenticated for at least one of the audiences, or
+// for the API server audiences when none are provided.
+func (r *Resources) VerifyToken(ctx context.Context, token string, audiences []string) (bool, error) {
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return false, fmt.Errorf("verify token: %w", err)
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: audiences},
+	}
+	review, err = clientset.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("verify token: %w", err)
+	}
+	return review.Status.Authenticated, nil
+}
+
+func bindingName(sa *corev1.ServiceAccount, roleName string) string {
+	return fmt.Sprintf("%s-%s", sa.Name, roleName)
+}
+
+// clusterBindingName returns the name of a cluster-scoped binding, which
+// must differ for service accounts with the same name in other namespaces
+func clusterBindingName(sa *corev1.ServiceAccount, roleName string) string {
+	return fmt.Sprintf("%s-%s-%s", sa.Namespace, sa.Name, roleName)
+}
+
+func serviceAccountSubject(sa *corev1.ServiceAccount) rbacv1.Subject {
+	return rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: sa.Name, Namespace: sa.Namespace}
+}
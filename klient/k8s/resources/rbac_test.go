@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTokenServer returns an API server that issues token `secret` for
+// service account default/tester and only authenticates that token.
+func newTokenServer(t *testing.T) *httptest.Server {
+	mux := newDiscoveryMux(t)
+	mux.HandleFunc("/api/v1/namespaces/default/serviceaccounts/tester/token", func(w http.ResponseWriter, r *http.Request) {
+		var request authenticationv1.TokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		if request.Spec.ExpirationSeconds == nil || *request.Spec.ExpirationSeconds != 600 {
+			t.Errorf("unexpected token expiration %v", request.Spec.ExpirationSeconds)
+		}
+		request.Status.Token = "secret"
+		writeJSON(t, w, http.StatusCreated, &request)
+	})
+	mux.HandleFunc("/apis/authentication.k8s.io/v1/tokenreviews", func(w http.ResponseWriter, r *http.Request) {
+		var review authenticationv1.TokenReview
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			t.Error(err)
+		}
+		review.Status.Authenticated = review.Spec.Token == "secret"
+		writeJSON(t, w, http.StatusCreated, &review)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestServiceAccountToken(t *testing.T) {
+	server := newTokenServer(t)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := res.GetServiceAccountToken(context.TODO(), "tester", "default", []string{"e2e"}, 600)
+	if err != nil {
+		t.Fatal("error while getting token", err)
+	}
+	if token != "secret" {
+		t.Errorf("unexpected token %q", token)
+	}
+
+	for token, expected := range map[string]bool{"secret": true, "forged": false} {
+		authenticated, err := res.VerifyToken(context.TODO(), token, []string{"e2e"})
+		if err != nil {
+			t.Fatal("error while verifying token", err)
+		}
+		if authenticated != expected {
+			t.Errorf("token %q: expected authenticated %t, got %t", token, expected, authenticated)
+		}
+	}
+}
+
+func TestBindClusterRole(t *testing.T) {
+	res := NewWithClient(&rest.Config{}, fake.NewClientBuilder().WithScheme(scheme.Scheme).Build())
+
+	// service accounts with the same name in different namespaces
+	for _, ns := range []string{"team-a", "team-b"} {
+		sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "tester", Namespace: ns}}
+		if err := res.BindClusterRole(context.TODO(), sa, "view"); err != nil {
+			t.Fatalf("error while binding cluster role for %s/%s: %s", ns, sa.Name, err)
+		}
+	}
+
+	for _, expected := range []string{"team-a-tester-view", "team-b-tester-view"} {
+		var binding rbacv1.ClusterRoleBinding
+		if err := res.Get(context.TODO(), expected, "", &binding); err != nil {
+			t.Errorf("error while getting cluster role binding %s: %s", expected, err)
+		}
+	}
+}
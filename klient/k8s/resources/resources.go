@@ -22,6 +22,7 @@ import (
 	"log"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 
@@ -43,6 +44,9 @@ type Resources struct {
 
 	// namespace for namespaced object requests
 	namespace string
+
+	// closers are the stop funcs of the running background operations
+	closers closers
 }
 
 // New instantiates the controller runtime client
@@ -72,11 +76,31 @@ func New(cfg *rest.Config) (*Resources, error) {
 	return res, nil
 }
 
+// NewWithClient returns a Resources value that uses client, i.e. a client
+// created with the controller-runtime fake package, for the object operations.
+// cfg is used by the operations that are not supported by the controller-runtime
+// client, such as GetLogs or ExecInPod.
+func NewWithClient(cfg *rest.Config, client cr.Client) *Resources {
+	return &Resources{
+		config: cfg,
+		scheme: client.Scheme(),
+		client: client,
+	}
+}
+
 func (r *Resources) WithNamespace(ns string) *Resources {
 	r.namespace = ns
 	return r
 }
 
+// InNamespace returns a Resources value, sharing the client of r, for the
+// namespaced requests (i.e. List) in ns. Unlike WithNamespace, r is left
+// unchanged, so callers using r concurrently are not affected. Background
+// operations started with the returned value are stopped by its own Close.
+func (r *Resources) InNamespace(ns string) *Resources {
+	return &Resources{config: r.config, scheme: r.scheme, client: r.client, namespace: ns}
+}
+
 func (r *Resources) Get(ctx context.Context, name, namespace string, obj k8s.Object) error {
 	return r.client.Get(ctx, cr.ObjectKey{Namespace: namespace, Name: name}, obj)
 }
@@ -84,13 +108,20 @@ func (r *Resources) Get(ctx context.Context, name, namespace string, obj k8s.Obj
 type CreateOption func(*metav1.CreateOptions)
 
 func (r *Resources) Create(ctx context.Context, obj k8s.Object, opts ...CreateOption) error {
-	createOptions := &metav1.CreateOptions{}
+	createOptions := metav1.CreateOptions{}
 	for _, fn := range opts {
-		fn(createOptions)
+		fn(&createOptions)
 	}
 
-	o := &cr.CreateOptions{Raw: createOptions}
+	return r.CreateWithOptions(ctx, obj, createOptions)
+}
 
+// CreateWithOptions creates obj using the provided create options.
+// This allows callers to set options such as DryRun or FieldManager directly.
+func (r *Resources) CreateWithOptions(ctx context.Context, obj k8s.Object, opts metav1.CreateOptions) error {
+	// DryRun and FieldManager must also be set on the controller-runtime
+	// options, otherwise they override the values set in Raw.
+	o := &cr.CreateOptions{DryRun: opts.DryRun, FieldManager: opts.FieldManager, Raw: &opts}
 	return r.client.Create(ctx, obj, o)
 }
 
@@ -118,8 +149,70 @@ func (r *Resources) Delete(ctx context.Context, obj k8s.Object, opts ...DeleteOp
 	return r.client.Delete(ctx, obj, o)
 }
 
+// DeleteCollectionOption is used to provide additional arguments to the
+// DeleteCollection call. It is implemented by ListOption, which selects the
+// objects to delete (i.e. WithLabelSelector), by DeleteOption (i.e.
+// WithGracePeriod) and by the option returned by WithDeleteNamespace.
+type DeleteCollectionOption interface {
+	applyToDeleteCollection(*deleteCollectionOptions)
+}
+
+type deleteCollectionOptions struct {
+	namespace     string
+	listOptions   metav1.ListOptions
+	deleteOptions metav1.DeleteOptions
+}
+
+func (fn ListOption) applyToDeleteCollection(o *deleteCollectionOptions) {
+	fn(&o.listOptions)
+}
+
+func (fn DeleteOption) applyToDeleteCollection(o *deleteCollectionOptions) {
+	fn(&o.deleteOptions)
+}
+
+type namespaceOption string
+
+func (ns namespaceOption) applyToDeleteCollection(o *deleteCollectionOptions) {
+	o.namespace = string(ns)
+}
+
+// WithDeleteNamespace sets the namespace of the objects deleted by DeleteCollection,
+// overriding the namespace of the Resources.
+func WithDeleteNamespace(ns string) DeleteCollectionOption {
+	return namespaceOption(ns)
+}
+
+// DeleteCollection deletes, in a single request, all the objects of the type of
+// obj in the namespace of the Resources (or set with WithDeleteNamespace) that match
+// the list options, i.e. all the pods labeled app=test:
+//
+//	res.DeleteCollection(ctx, &corev1.Pod{}, WithDeleteNamespace(ns), WithLabelSelector("app=test"))
+//
+// The resource of obj is resolved from its type using the scheme.
+func (r *Resources) DeleteCollection(ctx context.Context, obj k8s.Object, opts ...DeleteCollectionOption) error {
+	o := &deleteCollectionOptions{namespace: r.namespace}
+	for _, opt := range opts {
+		opt.applyToDeleteCollection(o)
+	}
+
+	// GracePeriodSeconds, PropagationPolicy and DryRun must also be set on
+	// the controller-runtime options, otherwise they override the values set in Raw.
+	deleteAllOf := &cr.DeleteAllOfOptions{
+		ListOptions: cr.ListOptions{Namespace: o.namespace, Raw: &o.listOptions},
+		DeleteOptions: cr.DeleteOptions{
+			GracePeriodSeconds: o.deleteOptions.GracePeriodSeconds,
+			PropagationPolicy:  o.deleteOptions.PropagationPolicy,
+			DryRun:             o.deleteOptions.DryRun,
+			Raw:                &o.deleteOptions,
+		},
+	}
+	return r.client.DeleteAllOf(ctx, obj, deleteAllOf)
+}
+
+// WithGracePeriod sets the grace period of the deletion, truncated to seconds.
 func WithGracePeriod(gpt time.Duration) DeleteOption {
-	t := gpt.Milliseconds()
+	t := int64(gpt.Seconds())
 	return func(do *metav1.DeleteOptions) { do.GracePeriodSeconds = &t }
 }
 
@@ -130,6 +223,8 @@ func WithDeletePropagation(prop string) DeleteOption {
 
 type ListOption func(*metav1.ListOptions)
 
+// List retrieves the objects of the type of objs. When the server paginates
+// the result set (i.e. a limit is set), all pages are retrieved and merged.
 func (r *Resources) List(ctx context.Context, objs k8s.ObjectList, opts ...ListOption) error {
 	listOptions := &metav1.ListOptions{}
 
@@ -137,12 +232,24 @@ func (r *Resources) List(ctx context.Context, objs k8s.ObjectList, opts ...ListO
 		fn(listOptions)
 	}
 
-	o := &cr.ListOptions{Raw: listOptions}
-	if r.namespace != "" {
-		o.Namespace = r.namespace
+	if err := r.listPage(ctx, r.namespace, objs, listOptions, ""); err != nil {
+		return err
+	}
+	if objs.GetContinue() == "" {
+		return nil
 	}
 
-	return r.client.List(ctx, objs, o)
+	// the result set is paginated: retrieve the remaining pages
+	items, err := meta.ExtractList(objs)
+	if err != nil {
+		return err
+	}
+	remaining, err := r.listAllPages(ctx, r.namespace, objs, listOptions, objs.GetContinue())
+	if err != nil {
+		return err
+	}
+	objs.SetContinue("")
+	return meta.SetList(objs, append(items, remaining...))
 }
 
 func WithLabelSelector(sel string) ListOption {
@@ -153,6 +260,12 @@ func WithFieldSelector(sel string) ListOption {
 	return func(lo *metav1.ListOptions) { lo.FieldSelector = sel }
 }
 
+// WithPageSize sets the maximum number of objects retrieved per list request.
+// List retrieves and merges all the pages.
+func WithPageSize(n int64) ListOption {
+	return func(lo *metav1.ListOptions) { lo.Limit = n }
+}
+
 func WithTimeout(to time.Duration) ListOption {
 	t := to.Milliseconds()
 	return func(lo *metav1.ListOptions) { lo.TimeoutSeconds = &t }
@@ -19,13 +19,22 @@ package resources
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/e2e-framework/klient/k8s"
 )
@@ -236,3 +245,528 @@ func TestListAllPods(t *testing.T) {
 
 	t.Logf("pod list contains %d pods", len(pods.Items))
 }
+
+func TestCordonUncordon(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := res.List(context.TODO(), nodes); err != nil {
+		t.Fatal("error while listing nodes", err)
+	}
+	if len(nodes.Items) == 0 {
+		t.Fatal("no nodes found")
+	}
+	node := &nodes.Items[0]
+
+	if err := res.Cordon(context.TODO(), node); err != nil {
+		t.Error("error while cordoning node", err)
+	}
+
+	var actual corev1.Node
+	if err := res.Get(context.TODO(), node.Name, "", &actual); err != nil {
+		t.Error("error while getting node", err)
+	}
+	if !actual.Spec.Unschedulable {
+		t.Error("node not cordoned")
+	}
+
+	if err := res.Uncordon(context.TODO(), node); err != nil {
+		t.Error("error while uncordoning node", err)
+	}
+
+	if err := res.Get(context.TODO(), node.Name, "", &actual); err != nil {
+		t.Error("error while getting node", err)
+	}
+	if actual.Spec.Unschedulable {
+		t.Error("node still cordoned")
+	}
+}
+
+func TestCreateWithOptions(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	depDryRun := getDeployment("dry-run-test-dep-name")
+	err = res.CreateWithOptions(context.TODO(), depDryRun, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	if err != nil {
+		t.Error("error while creating deployment with dry-run", err)
+	}
+
+	var depObj appsv1.Deployment
+	err = res.Get(context.TODO(), depDryRun.Name, namespace.Name, &depObj)
+	if err == nil {
+		t.Error("deployment created with dry-run should not exist")
+	}
+}
+
+func TestCreateServiceAccount(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	annotations := map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::111122223333:role/test"}
+	sa, err := res.CreateServiceAccount(context.TODO(), "test-sa", namespace.Name, annotations)
+	if err != nil {
+		t.Fatal("error while creating service account", err)
+	}
+
+	var saObj corev1.ServiceAccount
+	if err := res.Get(context.TODO(), sa.Name, namespace.Name, &saObj); err != nil {
+		t.Error("error while getting the service account", err)
+	}
+	if saObj.Annotations["eks.amazonaws.com/role-arn"] != annotations["eks.amazonaws.com/role-arn"] {
+		t.Error("service account annotation mismatch")
+	}
+
+	if err := res.BindClusterRole(context.TODO(), sa, "view"); err != nil {
+		t.Error("error while binding cluster role", err)
+	}
+
+	if err := res.BindRole(context.TODO(), sa, "test-role", namespace.Name); err != nil {
+		t.Error("error while binding role", err)
+	}
+}
+
+func TestApplyConfigMap(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	// applying twice must succeed, the second apply updates the data
+	for _, val := range []string{"v1", "v2"} {
+		if _, err := res.ApplyConfigMap(context.TODO(), "apply-test-cm", namespace.Name, map[string]string{"key": val}); err != nil {
+			t.Fatal("error while applying configmap", err)
+		}
+	}
+
+	var cm corev1.ConfigMap
+	if err := res.Get(context.TODO(), "apply-test-cm", namespace.Name, &cm); err != nil {
+		t.Error("error while getting the configmap", err)
+	}
+	if cm.Data["key"] != "v2" {
+		t.Error("configmap data mismatch, expected : ", "v2", "obtained :", cm.Data["key"])
+	}
+}
+
+func TestListAcrossNamespaces(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	deps := &appsv1.DeploymentList{}
+	err = res.ListAcrossNamespaces(context.TODO(), []string{"kube-system", namespace.Name, namespace.Name}, deps)
+	if err != nil {
+		t.Fatal("error while listing deployments", err)
+	}
+
+	hasDep, hasCoreDNS := 0, false
+	for _, item := range deps.Items {
+		if item.Name == dep.Name && item.Namespace == dep.Namespace {
+			hasDep++
+		}
+		if item.Name == "coredns" && item.Namespace == "kube-system" {
+			hasCoreDNS = true
+		}
+	}
+
+	if hasDep != 1 || !hasCoreDNS {
+		t.Error("unexpected deployments listed", hasDep, hasCoreDNS)
+	}
+}
+
+func TestUpdateOwnerReferences(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owner-cm", Namespace: namespace.Name}}
+	owned := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owned-cm", Namespace: namespace.Name}}
+	for _, cm := range []*corev1.ConfigMap{owner, owned} {
+		if err := res.Create(context.TODO(), cm); err != nil {
+			t.Fatal("error while creating configmap", err)
+		}
+	}
+
+	if err := res.UpdateOwnerReferences(context.TODO(), owned, []k8s.Object{owner}); err != nil {
+		t.Fatal("error while updating owner references", err)
+	}
+
+	refs := owned.GetOwnerReferences()
+	if len(refs) != 1 || refs[0].Kind != "ConfigMap" || refs[0].APIVersion != "v1" || refs[0].UID != owner.UID {
+		t.Error("unexpected owner references", refs)
+	}
+
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other-cm", Namespace: "default"}}
+	if err := res.UpdateOwnerReferences(context.TODO(), owned, []k8s.Object{other}); err == nil {
+		t.Error("expected error for owner in a different namespace")
+	}
+}
+
+func TestRequireServerVersion(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	info, err := res.GetAPIServerVersion()
+	if err != nil {
+		t.Fatal("error while getting server version", err)
+	}
+	if info.GitVersion == "" {
+		t.Error("server version not set")
+	}
+
+	if err := RequireServerVersion(context.TODO(), res, 1, 0); err != nil {
+		t.Error("unexpected error", err)
+	}
+
+	if err := RequireServerVersion(context.TODO(), res, 99, 0); err == nil {
+		t.Error("expected error for unreleased version")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nil")
+	}
+
+	dep := getDeployment("watch-test-dep-name")
+	if err := res.Create(context.TODO(), dep); err != nil {
+		t.Fatal("error while creating deployment", err)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- res.WaitForEvent(context.TODO(), &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: dep.Name, Namespace: dep.Namespace}}, string(watch.Modified), func(event watch.Event) bool {
+			d, ok := event.Object.(*appsv1.Deployment)
+			return ok && d.Labels["watch-key"] == "watch-val"
+		}, time.Minute)
+	}()
+
+	// give the watch time to start before the update
+	time.Sleep(2 * time.Second)
+	dep.Labels["watch-key"] = "watch-val"
+	if err := res.Update(context.TODO(), dep); err != nil {
+		t.Fatal("error while updating deployment", err)
+	}
+
+	if err := <-errs; err != nil {
+		t.Error("error while waiting for modified event", err)
+	}
+}
+
+func TestCreateRoleBinding(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "default", Namespace: namespace.Name}}
+	rb, err := res.CreateRoleBinding(context.TODO(), "test-rb", namespace.Name, "test-role", subjects)
+	if err != nil {
+		t.Fatal("error while creating role binding", err)
+	}
+
+	var rbObj rbacv1.RoleBinding
+	if err := res.Get(context.TODO(), rb.Name, namespace.Name, &rbObj); err != nil {
+		t.Error("error while getting the role binding", err)
+	}
+	if rbObj.RoleRef.Kind != "Role" || rbObj.RoleRef.Name != "test-role" || len(rbObj.Subjects) != 1 {
+		t.Error("unexpected role binding", rbObj.RoleRef, rbObj.Subjects)
+	}
+
+	crb, err := res.CreateClusterRoleBinding(context.TODO(), "test-crb", "view", subjects)
+	if err != nil {
+		t.Fatal("error while creating cluster role binding", err)
+	}
+	if crb.RoleRef.Kind != "ClusterRole" || crb.RoleRef.Name != "view" {
+		t.Error("unexpected cluster role binding", crb.RoleRef)
+	}
+}
+
+func TestApplyYAMLFile(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	if err := res.ApplyYAMLFile(context.TODO(), "testdata/deployment-service.yaml", WithNamespace(namespace.Name)); err != nil {
+		t.Fatal("error while applying yaml file", err)
+	}
+
+	var depObj appsv1.Deployment
+	if err := res.Get(context.TODO(), "yaml-test-dep", namespace.Name, &depObj); err != nil {
+		t.Error("error while getting the deployment", err)
+	}
+	var svcObj corev1.Service
+	if err := res.Get(context.TODO(), "yaml-test-svc", namespace.Name, &svcObj); err != nil {
+		t.Error("error while getting the service", err)
+	}
+}
+
+func TestApplyKustomize(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	if err := res.ApplyKustomize(context.TODO(), "testdata/kustomize/overlay", WithNamespace(namespace.Name)); err != nil {
+		t.Fatal("error while applying kustomization", err)
+	}
+
+	var cm corev1.ConfigMap
+	if err := res.Get(context.TODO(), "overlay-kustomize-cm", namespace.Name, &cm); err != nil {
+		t.Fatal("error while getting the configmap", err)
+	}
+	if cm.Data["env"] != "overlay" {
+		t.Error("overlay patch not applied, env:", cm.Data["env"])
+	}
+}
+
+func TestGetNodesByLabel(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	nodes, err := res.GetNodesByLabel(context.TODO(), map[string]string{"kubernetes.io/os": "linux"})
+	if err != nil {
+		t.Fatal("error while getting nodes by label", err)
+	}
+	if len(nodes.Items) == 0 {
+		t.Fatal("no linux nodes found")
+	}
+
+	node, err := res.GetNodeByName(context.TODO(), nodes.Items[0].Name)
+	if err != nil {
+		t.Fatal("error while getting node by name", err)
+	}
+	if node.Name != nodes.Items[0].Name {
+		t.Errorf("unexpected node %s", node.Name)
+	}
+
+	nodes, err = res.GetNodesByLabel(context.TODO(), map[string]string{"e2e-framework/missing": "true"})
+	if err != nil {
+		t.Fatal("error while getting nodes by label", err)
+	}
+	if len(nodes.Items) != 0 {
+		t.Errorf("expected no nodes, got %d", len(nodes.Items))
+	}
+}
+
+func TestDeleteCollection(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	for i := 0; i < 3; i++ {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("delete-collection-%d", i),
+			Namespace: namespace.Name,
+			Labels:    map[string]string{"test": "delete-collection"},
+		}}
+		if err := res.Create(context.TODO(), cm); err != nil {
+			t.Fatal("error while creating configmap", err)
+		}
+	}
+
+	err = res.DeleteCollection(context.TODO(), &corev1.ConfigMap{},
+		WithDeleteNamespace(namespace.Name), WithLabelSelector("test=delete-collection"), WithGracePeriod(0))
+	if err != nil {
+		t.Fatal("error while deleting configmaps", err)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := res.WithNamespace(namespace.Name).List(context.TODO(), &cms, WithLabelSelector("test=delete-collection")); err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	if len(cms.Items) != 0 {
+		t.Errorf("expected labeled configmaps to be deleted, %d left", len(cms.Items))
+	}
+}
+
+func TestDeleteCollectionGracePeriod(t *testing.T) {
+	var deleteOptions metav1.DeleteOptions
+	mux := newDiscoveryMux(t)
+	mux.HandleFunc("/api/v1/namespaces/default/configmaps", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected %s request", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&deleteOptions); err != nil {
+			t.Error(err)
+		}
+		writeJSON(t, w, http.StatusOK, &metav1.Status{Status: metav1.StatusSuccess})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = res.DeleteCollection(context.TODO(), &corev1.ConfigMap{}, WithDeleteNamespace("default"), WithGracePeriod(30*time.Second))
+	if err != nil {
+		t.Fatal("error while deleting configmaps", err)
+	}
+
+	if deleteOptions.GracePeriodSeconds == nil || *deleteOptions.GracePeriodSeconds != 30 {
+		t.Errorf("expected a grace period of 30 seconds, got %v", deleteOptions.GracePeriodSeconds)
+	}
+}
+
+func TestExecInPod(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "exec-test", Namespace: namespace.Name},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "busybox", Image: "busybox", Command: []string{"sleep", "3600"}},
+		}},
+	}
+	if err := res.Create(context.TODO(), pod); err != nil {
+		t.Fatal("error while creating pod", err)
+	}
+	defer func() { _ = res.Delete(context.TODO(), pod, WithGracePeriod(0)) }()
+
+	err = apimachinerywait.PollImmediate(time.Second, 2*time.Minute, func() (bool, error) {
+		if err := res.Get(context.TODO(), pod.Name, pod.Namespace, pod); err != nil {
+			return false, err
+		}
+		return pod.Status.Phase == corev1.PodRunning, nil
+	})
+	if err != nil {
+		t.Fatal("pod not running", err)
+	}
+
+	stdout, _, err := res.ExecInPod(context.TODO(), namespace.Name, pod.Name, "busybox", []string{"echo", "hello"})
+	if err != nil {
+		t.Fatal("error while executing command", err)
+	}
+	if stdout != "hello\n" {
+		t.Errorf("unexpected stdout %q", stdout)
+	}
+
+	stdout, _, err = res.ExecInPod(context.TODO(), namespace.Name, pod.Name, "busybox", []string{"cat"}, WithStdin(strings.NewReader("from stdin")))
+	if err != nil {
+		t.Fatal("error while executing command", err)
+	}
+	if stdout != "from stdin" {
+		t.Errorf("unexpected stdout %q", stdout)
+	}
+
+	if _, _, err := res.ExecInPod(context.TODO(), namespace.Name, pod.Name, "busybox", []string{"false"}); err == nil {
+		t.Error("expected an error for a failing command")
+	}
+}
+
+func TestPortForwardService(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	labels := map[string]string{"app": "port-forward-test"}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "port-forward-test", Namespace: namespace.Name, Labels: labels},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "nginx", Image: "nginx", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 80}}},
+		}},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "port-forward-test", Namespace: namespace.Name},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports:    []corev1.ServicePort{{Port: 8080, TargetPort: intstr.FromString("http")}},
+		},
+	}
+	for _, obj := range []k8s.Object{pod, svc} {
+		if err := res.Create(context.TODO(), obj); err != nil {
+			t.Fatal("error while creating object", err)
+		}
+		obj := obj
+		defer func() { _ = res.Delete(context.TODO(), obj) }()
+	}
+
+	err = apimachinerywait.PollImmediate(time.Second, 2*time.Minute, func() (bool, error) {
+		if err := res.Get(context.TODO(), pod.Name, pod.Namespace, pod); err != nil {
+			return false, err
+		}
+		return pod.Status.Phase == corev1.PodRunning, nil
+	})
+	if err != nil {
+		t.Fatal("pod not running", err)
+	}
+
+	cancel, err := res.PortForwardService(context.TODO(), svc, 18080)
+	if err != nil {
+		t.Fatal("error while forwarding port", err)
+	}
+	defer cancel()
+
+	resp, err := http.Get("http://localhost:18080")
+	if err != nil {
+		t.Fatal("error while getting forwarded port", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	res, err := New(cfg)
+	if err != nil {
+		t.Errorf("config is nill")
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "diff-test", Namespace: namespace.Name},
+		Data:       map[string]string{"key": "old"},
+	}
+	diff, err := res.Diff(context.TODO(), cm)
+	if err != nil {
+		t.Fatal("error while diffing missing configmap", err)
+	}
+	if !strings.Contains(diff, "+  key: old") {
+		t.Errorf("expected the configmap to be added, got:\n%s", diff)
+	}
+
+	if err := res.Create(context.TODO(), cm); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+	defer func() { _ = res.Delete(context.TODO(), cm) }()
+
+	changed := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "diff-test", Namespace: namespace.Name},
+		Data:       map[string]string{"key": "new"},
+	}
+	diff, err = res.Diff(context.TODO(), changed)
+	if err != nil {
+		t.Fatal("error while diffing configmap", err)
+	}
+	if !strings.Contains(diff, "-  key: old") || !strings.Contains(diff, "+  key: new") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+
+	var live corev1.ConfigMap
+	if err := res.Get(context.TODO(), cm.Name, cm.Namespace, &live); err != nil {
+		t.Fatal("error while getting configmap", err)
+	}
+	if live.Data["key"] != "old" {
+		t.Error("diff should not change the live object")
+	}
+}
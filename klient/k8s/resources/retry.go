@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+type retryOptions struct {
+	interval time.Duration
+	factor   float64
+}
+
+// RetryOption is used to provide additional arguments to the GetWithRetry call.
+type RetryOption func(*retryOptions)
+
+// WithRetryBackoff sets the initial interval between retries and the
+// factor by which the interval is multiplied after each retry.
+func WithRetryBackoff(interval time.Duration, factor float64) RetryOption {
+	return func(ro *retryOptions) {
+		ro.interval = interval
+		ro.factor = factor
+	}
+}
+
+// GetWithRetry retrieves the named object, retrying up to maxRetries times,
+// with exponential backoff, when the API server returns a retriable error
+// (429 TooManyRequests, 500 InternalError, or 503 ServiceUnavailable).
+// The last error is returned when all retries are exhausted.
+func (r *Resources) GetWithRetry(ctx context.Context, name, namespace string, obj k8s.Object, maxRetries int, opts ...RetryOption) error {
+	retryOpts := &retryOptions{interval: 500 * time.Millisecond, factor: 2.0}
+	for _, fn := range opts {
+		fn(retryOpts)
+	}
+
+	backoff := wait.Backoff{Duration: retryOpts.interval, Factor: retryOpts.factor, Steps: maxRetries + 1}
+
+	var lastErr error
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func() (bool, error) {
+		lastErr = r.Get(ctx, name, namespace, obj)
+		switch {
+		case lastErr == nil:
+			return true, nil
+		case isRetriable(lastErr):
+			return false, nil
+		default:
+			return false, lastErr
+		}
+	})
+
+	if errors.Is(err, wait.ErrWaitTimeout) && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
+
+func isRetriable(err error) bool {
+	return apierrors.IsTooManyRequests(err) || apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err)
+}
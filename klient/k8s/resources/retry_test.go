@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// writeJSON writes obj, encoded as JSON, as the response
+func writeJSON(t *testing.T, w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		t.Error(err)
+	}
+}
+
+// newDiscoveryMux returns a mux that serves the discovery
+// documents of an API server exposing configmaps and pods.
+func newDiscoveryMux(t *testing.T) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, w, http.StatusOK, &metav1.APIVersions{Versions: []string{"v1"}})
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, w, http.StatusOK, &metav1.APIGroupList{})
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, w, http.StatusOK, &metav1.APIResourceList{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"get", "list"}},
+				{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"get", "list"}},
+			},
+		})
+	})
+	return mux
+}
+
+// newFlakyServer returns an API server that serves the discovery
+// documents and answers the first n requests for configmap `test-cm`
+// with the provided status code.
+func newFlakyServer(t *testing.T, n, code int) (*httptest.Server, *int) {
+	calls := 0
+	mux := newDiscoveryMux(t)
+	mux.HandleFunc("/api/v1/namespaces/default/configmaps/test-cm", func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		if calls <= n {
+			writeJSON(t, w, code, &metav1.Status{Status: metav1.StatusFailure, Code: int32(code)})
+			return
+		}
+		writeJSON(t, w, http.StatusOK, &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cm", Namespace: "default"},
+		})
+	})
+
+	return httptest.NewServer(mux), &calls
+}
+
+func TestGetWithRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		code       int
+		maxRetries int
+		calls      int
+		shouldFail bool
+	}{
+		{name: "no failures", failures: 0, code: http.StatusServiceUnavailable, maxRetries: 3, calls: 1},
+		{name: "retry on 503", failures: 2, code: http.StatusServiceUnavailable, maxRetries: 3, calls: 3},
+		{name: "retry on 429", failures: 2, code: http.StatusTooManyRequests, maxRetries: 3, calls: 3},
+		{name: "retry on 500", failures: 1, code: http.StatusInternalServerError, maxRetries: 3, calls: 2},
+		{name: "retries exhausted", failures: 5, code: http.StatusServiceUnavailable, maxRetries: 2, calls: 3, shouldFail: true},
+		{name: "not retriable", failures: 1, code: http.StatusForbidden, maxRetries: 3, calls: 1, shouldFail: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, calls := newFlakyServer(t, test.failures, test.code)
+			defer server.Close()
+
+			res, err := New(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var cm corev1.ConfigMap
+			err = res.GetWithRetry(context.TODO(), "test-cm", "default", &cm, test.maxRetries, WithRetryBackoff(time.Millisecond, 2))
+			if test.shouldFail && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !test.shouldFail && err != nil {
+				t.Error("unexpected error", err)
+			}
+			if *calls != test.calls {
+				t.Errorf("unexpected number of calls: %d, expected %d", *calls, test.calls)
+			}
+		})
+	}
+}
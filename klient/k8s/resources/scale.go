@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// Scale sets the number of replicas of a *appsv1.Deployment, *appsv1.StatefulSet
+// or *appsv1.ReplicaSet by patching its scale subresource, without updating the
+// rest of the object. It does not wait for the replicas to be scaled.
+func (r *Resources) Scale(ctx context.Context, obj k8s.Object, replicas int32) error {
+	var resource string
+	switch obj.(type) {
+	case *appsv1.Deployment:
+		resource = "deployments"
+	case *appsv1.StatefulSet:
+		resource = "statefulsets"
+	case *appsv1.ReplicaSet:
+		resource = "replicasets"
+	default:
+		return fmt.Errorf("scale: unsupported object type %T", obj)
+	}
+
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return fmt.Errorf("scale %s %s/%s: %w", resource, obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": replicas},
+	})
+	if err != nil {
+		return err
+	}
+
+	scale := &autoscalingv1.Scale{}
+	err = clientset.AppsV1().RESTClient().Patch(types.MergePatchType).
+		Namespace(obj.GetNamespace()).
+		Resource(resource).
+		Name(obj.GetName()).
+		SubResource("scale").
+		Body(patch).
+		Do(ctx).
+		Into(scale)
+	if err != nil {
+		return fmt.Errorf("scale %s %s/%s: %w", resource, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
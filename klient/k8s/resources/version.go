@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+)
+
+// GetAPIServerVersion returns the version of the API server
+func (r *Resources) GetAPIServerVersion() (*version.Info, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(r.config)
+	if err != nil {
+		return nil, err
+	}
+	return client.ServerVersion()
+}
+
+// RequireServerVersion returns a descriptive error when the version of
+// the API server is older than major.minor. It can be used in feature
+// setup steps to guard tests that require a recent cluster.
+func RequireServerVersion(_ context.Context, res *Resources, major, minor int) error {
+	info, err := res.GetAPIServerVersion()
+	if err != nil {
+		return fmt.Errorf("require server version: %w", err)
+	}
+
+	serverMajor, serverMinor, err := parseVersionInfo(info)
+	if err != nil {
+		return fmt.Errorf("require server version: %w", err)
+	}
+
+	if serverMajor < major || (serverMajor == major && serverMinor < minor) {
+		return fmt.Errorf("server version %s is older than required version %d.%d", info.GitVersion, major, minor)
+	}
+	return nil
+}
+
+// parseVersionInfo returns the numeric major and minor versions, ignoring
+// provider-specific suffixes such as the "+" in minor version "21+".
+func parseVersionInfo(info *version.Info) (major, minor int, err error) {
+	if major, err = strconv.Atoi(strings.TrimRight(info.Major, "+")); err != nil {
+		return 0, 0, fmt.Errorf("invalid major version %q: %w", info.Major, err)
+	}
+	if minor, err = strconv.Atoi(strings.TrimRight(info.Minor, "+")); err != nil {
+		return 0, 0, fmt.Errorf("invalid minor version %q: %w", info.Minor, err)
+	}
+	return major, minor, nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// WatchOption is used to provide additional arguments to the Watch call.
+// Watches accept the same options as lists (i.e. WithLabelSelector).
+type WatchOption = ListOption
+
+// WithResourceVersion sets the resource version from which a watch (or list) starts.
+func WithResourceVersion(rv string) ListOption {
+	return func(lo *metav1.ListOptions) { lo.ResourceVersion = rv }
+}
+
+// Watch starts a watch on the objects of the type of obj, in the namespace of
+// obj (or the namespace of the Resources when not set). When obj has a name,
+// only the events of that object are watched. The events carry typed objects.
+// The watch runs until it is stopped, ctx is done or r is closed (see Close).
+func (r *Resources) Watch(ctx context.Context, obj k8s.Object, opts ...WatchOption) (watch.Interface, error) {
+	listOptions := &metav1.ListOptions{}
+	for _, fn := range opts {
+		fn(listOptions)
+	}
+
+	if obj.GetName() != "" {
+		nameSelector := fields.OneTermEqualSelector("metadata.name", obj.GetName()).String()
+		if listOptions.FieldSelector != "" {
+			nameSelector = fmt.Sprintf("%s,%s", nameSelector, listOptions.FieldSelector)
+		}
+		listOptions.FieldSelector = nameSelector
+	}
+
+	objList, err := r.listTypeFor(obj)
+	if err != nil {
+		return nil, fmt.Errorf("watch: %w", err)
+	}
+
+	client, err := cr.NewWithWatch(r.config, cr.Options{Scheme: r.scheme})
+	if err != nil {
+		return nil, fmt.Errorf("watch: %w", err)
+	}
+
+	o := &cr.ListOptions{Raw: listOptions, Namespace: obj.GetNamespace()}
+	if o.Namespace == "" {
+		o.Namespace = r.namespace
+	}
+
+	w, err := client.Watch(ctx, objList, o)
+	if err != nil {
+		return nil, err
+	}
+	return &trackedWatch{Interface: w, remove: r.closers.add(w.Stop)}, nil
+}
+
+// WaitForEvent watches obj (see Watch) until an event of type eventType
+// (i.e. MODIFIED), for which matchFn returns true, is received. An empty
+// eventType matches any event type and a nil matchFn matches any event.
+// It returns an error if no matching event is received within timeout.
+func (r *Resources) WaitForEvent(ctx context.Context, obj k8s.Object, eventType string, matchFn func(watch.Event) bool, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	w, err := r.Watch(ctx, obj)
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return fmt.Errorf("wait for %s event: watch closed", eventType)
+			}
+			if eventType != "" && string(event.Type) != eventType {
+				continue
+			}
+			if matchFn == nil || matchFn(event) {
+				return nil
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("wait for %s event: %w", eventType, ctx.Err())
+		}
+	}
+}
+
+// listTypeFor returns an empty list of the type of obj.
+func (r *Resources) listTypeFor(obj k8s.Object) (cr.ObjectList, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return nil, err
+	}
+	gvk.Kind = gvk.Kind + "List"
+
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk)
+		return list, nil
+	}
+
+	list, err := r.scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	objList, ok := list.(cr.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("unexpected list type %T", list)
+	}
+	return objList, nil
+}
@@ -0,0 +1,645 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conditions provides condition functions, of type
+// apimachinery wait.ConditionFunc, that can be polled to wait
+// for API resources to reach an expected state.
+package conditions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vladimirvivien/gexe"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// Condition is used to create condition functions
+// backed by a *resources.Resources value.
+type Condition struct {
+	resources *resources.Resources
+	e         *gexe.Echo
+}
+
+// New creates a new Condition that uses r to retrieve the
+// API resources being evaluated.
+func New(r *resources.Resources) *Condition {
+	return &Condition{resources: r, e: gexe.New()}
+}
+
+// ResourceVersionChanged returns a condition function that fetches the object
+// and returns true when its resource version differs from originalRV, meaning
+// the object has been updated.
+func (c *Condition) ResourceVersionChanged(obj k8s.Object, originalRV string) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			return false, err
+		}
+		return obj.GetResourceVersion() != originalRV, nil
+	}
+}
+
+// GenerationChanged returns a condition function that fetches the object and
+// returns true when its generation differs from originalGen, meaning the
+// object's spec has been updated.
+func (c *Condition) GenerationChanged(obj k8s.Object, originalGen int64) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			return false, err
+		}
+		return obj.GetGeneration() != originalGen, nil
+	}
+}
+
+// ResourceScaled returns a condition function that fetches the object and
+// returns true when the number of replicas returned by scaleFetcher for the
+// object, i.e. its ready replicas, equals replicas.
+func (c *Condition) ResourceScaled(obj k8s.Object, scaleFetcher func(k8s.Object) int32, replicas int32) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			return false, err
+		}
+		return scaleFetcher(obj) == replicas, nil
+	}
+}
+
+// TokenReviewSucceeds returns a condition function that submits a TokenReview
+// for the token, with the audiences, and returns true when the API server
+// authenticates the token.
+func (c *Condition) TokenReviewSucceeds(token string, audiences []string) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		review := &authenticationv1.TokenReview{
+			Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: audiences},
+		}
+		if err := c.resources.Create(context.TODO(), review); err != nil {
+			return false, err
+		}
+		return review.Status.Authenticated, nil
+	}
+}
+
+// EndpointSliceReady returns a condition function that lists the EndpointSlices
+// of service svcName (using label kubernetes.io/service-name) and returns true
+// when the number of ready endpoints, across all slices, reaches minEndpoints.
+func (c *Condition) EndpointSliceReady(svcName, namespace string, minEndpoints int) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		var slices discoveryv1.EndpointSliceList
+		selector := resources.WithLabelSelector(discoveryv1.LabelServiceName + "=" + svcName)
+		if err := c.resources.InNamespace(namespace).List(context.TODO(), &slices, selector); err != nil {
+			return false, err
+		}
+
+		ready := 0
+		for _, slice := range slices.Items {
+			for _, endpoint := range slice.Endpoints {
+				// a nil Ready value is an unknown state that should be interpreted as ready
+				if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+					ready++
+				}
+			}
+		}
+
+		return ready >= minEndpoints, nil
+	}
+}
+
+// PVCCapacityAtLeast returns a condition function that fetches the PersistentVolumeClaim
+// and returns true when its storage capacity, reported in its status, is at least size.
+// This can be used to wait for the completion of a volume expansion.
+func (c *Condition) PVCCapacityAtLeast(pvc *corev1.PersistentVolumeClaim, size resource.Quantity) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), pvc.GetName(), pvc.GetNamespace(), pvc); err != nil {
+			return false, err
+		}
+
+		capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+		if !ok {
+			return false, nil
+		}
+
+		return capacity.Cmp(size) >= 0, nil
+	}
+}
+
+// PersistentVolumeReclaimPolicyIs returns a condition function that fetches the
+// PersistentVolume and returns true when its reclaim policy is policy.
+func (c *Condition) PersistentVolumeReclaimPolicyIs(pv *corev1.PersistentVolume, policy corev1.PersistentVolumeReclaimPolicy) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), pv.GetName(), "", pv); err != nil {
+			return false, err
+		}
+		return pv.Spec.PersistentVolumeReclaimPolicy == policy, nil
+	}
+}
+
+// PVStatusPhase returns a condition function that fetches the
+// PersistentVolume and returns true when its status phase is phase.
+func (c *Condition) PVStatusPhase(pv *corev1.PersistentVolume, phase corev1.PersistentVolumePhase) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), pv.GetName(), "", pv); err != nil {
+			return false, err
+		}
+		return pv.Status.Phase == phase, nil
+	}
+}
+
+// PersistentVolumeAvailable returns a condition function that fetches the
+// PersistentVolume and returns true when it is available to be claimed.
+func (c *Condition) PersistentVolumeAvailable(pv *corev1.PersistentVolume) apimachinerywait.ConditionFunc {
+	return c.PVStatusPhase(pv, corev1.VolumeAvailable)
+}
+
+// PersistentVolumeClaimBound returns a condition function that fetches the
+// PersistentVolumeClaim and returns true when it is bound to a volume.
+func (c *Condition) PersistentVolumeClaimBound(pvc *corev1.PersistentVolumeClaim) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), pvc.GetName(), pvc.GetNamespace(), pvc); err != nil {
+			return false, err
+		}
+		return pvc.Status.Phase == corev1.ClaimBound, nil
+	}
+}
+
+// DeploymentAvailable returns a condition function that fetches the Deployment
+// and returns true when its latest generation is rolled out: all of its
+// replicas are updated and available, and no old replicas remain.
+func (c *Condition) DeploymentAvailable(dep *appsv1.Deployment) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), dep.GetName(), dep.GetNamespace(), dep); err != nil {
+			return false, err
+		}
+
+		status := dep.Status
+		replicas := desiredReplicas(dep.Spec.Replicas)
+		return status.ObservedGeneration >= dep.Generation &&
+			status.UpdatedReplicas == replicas &&
+			status.Replicas == replicas &&
+			status.AvailableReplicas == replicas, nil
+	}
+}
+
+// StatefulSetReady returns a condition function that fetches the StatefulSet
+// and returns true when its latest generation is rolled out: all of its
+// replicas are updated to the current revision and ready.
+func (c *Condition) StatefulSetReady(sts *appsv1.StatefulSet) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), sts.GetName(), sts.GetNamespace(), sts); err != nil {
+			return false, err
+		}
+
+		status := sts.Status
+		replicas := desiredReplicas(sts.Spec.Replicas)
+		return status.ObservedGeneration >= sts.Generation &&
+			status.UpdatedReplicas == replicas &&
+			status.ReadyReplicas == replicas &&
+			status.CurrentRevision == status.UpdateRevision, nil
+	}
+}
+
+// DaemonSetReady returns a condition function that fetches the DaemonSet and
+// returns true when its number of ready pods equals the number of nodes that
+// should run the daemon pod.
+func (c *Condition) DaemonSetReady(ds *appsv1.DaemonSet) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), ds.GetName(), ds.GetNamespace(), ds); err != nil {
+			return false, err
+		}
+		return ds.Status.NumberReady == ds.Status.DesiredNumberScheduled, nil
+	}
+}
+
+// DaemonSetMinimumReady returns a condition function that fetches the DaemonSet
+// and returns true when at least minFraction (i.e. 0.8) of its desired pods are
+// ready. This is useful during rolling updates.
+func (c *Condition) DaemonSetMinimumReady(ds *appsv1.DaemonSet, minFraction float64) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), ds.GetName(), ds.GetNamespace(), ds); err != nil {
+			return false, err
+		}
+		desired := ds.Status.DesiredNumberScheduled
+		if desired == 0 {
+			return true, nil
+		}
+		return float64(ds.Status.NumberReady)/float64(desired) >= minFraction, nil
+	}
+}
+
+// desiredReplicas returns the number of replicas of a workload spec,
+// which defaults to 1 when not set.
+func desiredReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// JobCompleted returns a condition function that fetches the Job and returns
+// true when its number of succeeded pods reaches Spec.Completions (or 1 when
+// Spec.Completions is not set).
+func (c *Condition) JobCompleted(job *batchv1.Job) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), job.GetName(), job.GetNamespace(), job); err != nil {
+			return false, err
+		}
+
+		completions := int32(1)
+		if job.Spec.Completions != nil {
+			completions = *job.Spec.Completions
+		}
+		return job.Status.Succeeded >= completions, nil
+	}
+}
+
+// JobFailed returns a condition function that fetches the Job and returns true
+// when its number of failed pods exceeds Spec.BackoffLimit (6 when not set).
+func (c *Condition) JobFailed(job *batchv1.Job) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), job.GetName(), job.GetNamespace(), job); err != nil {
+			return false, err
+		}
+
+		backoffLimit := int32(6) // API server default
+		if job.Spec.BackoffLimit != nil {
+			backoffLimit = *job.Spec.BackoffLimit
+		}
+		return job.Status.Failed > backoffLimit, nil
+	}
+}
+
+// AllPodsReady returns a condition function that lists the pods, in namespace,
+// matching the label selector and returns true when there is at least one pod
+// and the ContainersReady condition of every pod is true.
+func (c *Condition) AllPodsReady(namespace, selector string) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		var pods corev1.PodList
+		if err := c.resources.InNamespace(namespace).List(context.TODO(), &pods, resources.WithLabelSelector(selector)); err != nil {
+			return false, err
+		}
+		if len(pods.Items) == 0 {
+			return false, nil
+		}
+
+		for _, pod := range pods.Items {
+			if !podConditionTrue(&pod, corev1.ContainersReady) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+func podConditionTrue(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == conditionType {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// PodInitContainerTerminated returns a condition function that fetches the pod
+// and returns true when its init container containerName has terminated.
+func (c *Condition) PodInitContainerTerminated(pod *corev1.Pod, containerName string) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		state, err := c.initContainerTerminatedState(pod, containerName)
+		if err != nil {
+			return false, err
+		}
+		return state != nil, nil
+	}
+}
+
+// PodInitContainerSucceeded returns a condition function that fetches the pod and
+// returns true when its init container containerName has terminated with exit code 0.
+func (c *Condition) PodInitContainerSucceeded(pod *corev1.Pod, containerName string) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		state, err := c.initContainerTerminatedState(pod, containerName)
+		if err != nil {
+			return false, err
+		}
+		return state != nil && state.ExitCode == 0, nil
+	}
+}
+
+// initContainerTerminatedState fetches the pod and returns the terminated state of
+// the named init container, or nil if the container has not terminated yet.
+func (c *Condition) initContainerTerminatedState(pod *corev1.Pod, containerName string) (*corev1.ContainerStateTerminated, error) {
+	if err := c.resources.Get(context.TODO(), pod.GetName(), pod.GetNamespace(), pod); err != nil {
+		return nil, err
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name == containerName {
+			return status.State.Terminated, nil
+		}
+	}
+	return nil, nil
+}
+
+// crdVersions are the apiextensions versions of CustomResourceDefinition,
+// in order of preference
+var crdVersions = []string{"v1", "v1beta1"}
+
+// CRDEstablished returns a condition function that fetches the named
+// CustomResourceDefinition and returns true when both its NamesAccepted and
+// Established conditions are true, meaning its resources are served and
+// instances can be created. Both the apiextensions.k8s.io v1 and v1beta1
+// versions are supported. The condition is false while the CRD does not exist.
+func (c *Condition) CRDEstablished(crdName string) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		crd, err := c.getCRD(crdName)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		conditions, _, err := unstructured.NestedSlice(crd.Object, "status", "conditions")
+		if err != nil {
+			return false, err
+		}
+		var established, namesAccepted bool
+		for _, cond := range conditions {
+			condition, ok := cond.(map[string]interface{})
+			if !ok || condition["status"] != "True" {
+				continue
+			}
+			switch condition["type"] {
+			case "Established":
+				established = true
+			case "NamesAccepted":
+				namesAccepted = true
+			}
+		}
+		return established && namesAccepted, nil
+	}
+}
+
+// getCRD fetches the named CustomResourceDefinition, as an unstructured
+// object, using the first apiextensions version served by the API server.
+func (c *Condition) getCRD(crdName string) (*unstructured.Unstructured, error) {
+	var err error
+	for _, version := range crdVersions {
+		crd := &unstructured.Unstructured{}
+		crd.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: version, Kind: "CustomResourceDefinition"})
+		err = c.resources.Get(context.TODO(), crdName, "", crd)
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		return crd, err
+	}
+	return nil, err
+}
+
+// ClusterVersionAtLeast returns a condition function that returns true when
+// the version of the API server is major.minor or newer, i.e. once the
+// control plane has been upgraded.
+func (c *Condition) ClusterVersionAtLeast(major, minor int) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		v, err := c.clusterVersion()
+		if err != nil {
+			return false, err
+		}
+		return v.Major() > uint(major) || (v.Major() == uint(major) && v.Minor() >= uint(minor)), nil
+	}
+}
+
+// ClusterVersionExactly returns a condition function that returns true
+// when the version of the API server is major.minor.patch, ignoring
+// pre-release and build metadata (i.e. v1.21.1+k3s1 is 1.21.1).
+func (c *Condition) ClusterVersionExactly(major, minor, patch int) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		v, err := c.clusterVersion()
+		if err != nil {
+			return false, err
+		}
+		return v.Major() == uint(major) && v.Minor() == uint(minor) && v.Patch() == uint(patch), nil
+	}
+}
+
+// clusterVersion returns the parsed git version of the API server.
+func (c *Condition) clusterVersion() (*utilversion.Version, error) {
+	info, err := c.resources.GetAPIServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	return utilversion.ParseGeneric(info.GitVersion)
+}
+
+// ServiceExternalIPAssigned returns a condition function that fetches the
+// Service and returns true when its load balancer has been assigned an
+// external IP or hostname.
+func (c *Condition) ServiceExternalIPAssigned(svc *corev1.Service) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), svc.GetName(), svc.GetNamespace(), svc); err != nil {
+			return false, err
+		}
+		ingress := svc.Status.LoadBalancer.Ingress
+		return len(ingress) > 0 && (ingress[0].IP != "" || ingress[0].Hostname != ""), nil
+	}
+}
+
+// ServiceReady returns a condition function that fetches the Service and
+// returns true when it can serve traffic: a Service with a selector must have
+// at least one ready endpoint and a LoadBalancer Service must also have been
+// assigned an external IP (see ServiceExternalIPAssigned). ExternalName
+// Services are always ready.
+func (c *Condition) ServiceReady(svc *corev1.Service) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), svc.GetName(), svc.GetNamespace(), svc); err != nil {
+			return false, err
+		}
+		if svc.Spec.Type == corev1.ServiceTypeExternalName {
+			return true, nil
+		}
+
+		if len(svc.Spec.Selector) > 0 {
+			if ready, err := c.EndpointSliceReady(svc.Name, svc.Namespace, 1)(); err != nil || !ready {
+				return false, err
+			}
+		}
+		if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			return c.ServiceExternalIPAssigned(svc)()
+		}
+		return true, nil
+	}
+}
+
+// AllPodsInNamespaceTerminated returns a condition function that lists the pods
+// in namespace and returns true once none are left, meaning all of them have
+// terminated and been removed. This can be used to verify workload cleanup
+// without waiting for the namespace itself to be deleted.
+func (c *Condition) AllPodsInNamespaceTerminated(namespace string) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		var pods corev1.PodList
+		if err := c.resources.InNamespace(namespace).List(context.TODO(), &pods); err != nil {
+			return false, err
+		}
+		return len(pods.Items) == 0, nil
+	}
+}
+
+// IngressAddressAssigned returns a condition function that fetches the Ingress
+// and returns true when the ingress controller has assigned it an address.
+func (c *Condition) IngressAddressAssigned(ing *networkingv1.Ingress) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), ing.GetName(), ing.GetNamespace(), ing); err != nil {
+			return false, err
+		}
+		return ingressAddress(ing) != "", nil
+	}
+}
+
+// IngressAvailable returns a condition function that fetches the Ingress and
+// sends an HTTP GET request, using httpClient (http.DefaultClient when nil), to
+// its address for each host and path of its rules. It returns true when all the
+// requests get a response with a non-5xx status code. Hosts listed in the
+// Ingress TLS section are requested over HTTPS.
+func (c *Condition) IngressAvailable(ing *networkingv1.Ingress, httpClient *http.Client) apimachinerywait.ConditionFunc {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return func() (done bool, err error) {
+		if err := c.resources.Get(context.TODO(), ing.GetName(), ing.GetNamespace(), ing); err != nil {
+			return false, err
+		}
+		address := ingressAddress(ing)
+		if address == "" {
+			return false, nil
+		}
+
+		requests, err := ingressRequests(ing, address)
+		if err != nil {
+			return false, err
+		}
+		for _, req := range requests {
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				// the ingress controller may not be routing requests yet
+				return false, nil
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// ingressAddress returns the first IP or hostname assigned to the Ingress.
+func ingressAddress(ing *networkingv1.Ingress) string {
+	for _, ingress := range ing.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			return ingress.IP
+		}
+		if ingress.Hostname != "" {
+			return ingress.Hostname
+		}
+	}
+	return ""
+}
+
+// ingressRequests returns a GET request, sent to address, for each host and
+// path combination of the Ingress rules, or for "/" when the Ingress only has
+// a default backend. It fails when a request cannot be created for a rule, i.e.
+// for an invalid path, so that no rule is left unchecked.
+func ingressRequests(ing *networkingv1.Ingress, address string) ([]*http.Request, error) {
+	rules := ing.Spec.Rules
+	if len(rules) == 0 {
+		rules = []networkingv1.IngressRule{{}}
+	}
+
+	tlsHosts := make(map[string]bool)
+	for _, tls := range ing.Spec.TLS {
+		for _, host := range tls.Hosts {
+			tlsHosts[host] = true
+		}
+	}
+
+	var requests []*http.Request
+	for _, rule := range rules {
+		scheme := "http"
+		if tlsHosts[rule.Host] {
+			scheme = "https"
+		}
+		paths := []string{"/"}
+		if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+			paths = paths[:0]
+			for _, path := range rule.HTTP.Paths {
+				if path.Path == "" {
+					path.Path = "/"
+				}
+				paths = append(paths, path.Path)
+			}
+		}
+
+		for _, path := range paths {
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s://%s%s", scheme, address, path), nil)
+			if err != nil {
+				return nil, fmt.Errorf("ingress %s/%s: request for host %q and path %q: %w", ing.GetNamespace(), ing.GetName(), rule.Host, path, err)
+			}
+			// route the request as if it was sent to the rule host
+			req.Host = rule.Host
+			requests = append(requests, req)
+		}
+	}
+	return requests, nil
+}
+
+// HelmReleaseDeployed returns a condition function that runs
+// `helm status <releaseName> -n <namespace> -o json` and returns true when
+// the status of the release (its info.status field) is deployed. The
+// condition is false while the release does not exist. helm runs with the
+// kubeconfig of its environment, i.e. the KUBECONFIG environment variable.
+func (c *Condition) HelmReleaseDeployed(namespace, releaseName string) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		p := c.e.RunProc(fmt.Sprintf("helm status %s -n %s -o json", releaseName, namespace))
+		if p.Err() != nil {
+			if strings.Contains(p.Result(), "release: not found") {
+				return false, nil
+			}
+			return false, fmt.Errorf("helm status %s/%s: %s: %w", namespace, releaseName, p.Result(), p.Err())
+		}
+
+		var release struct {
+			Info struct {
+				Status string `json:"status"`
+			} `json:"info"`
+		}
+		if err := json.Unmarshal([]byte(p.Result()), &release); err != nil {
+			return false, fmt.Errorf("helm status %s/%s: decode status: %w", namespace, releaseName, err)
+		}
+		return release.Info.Status == "deployed", nil
+	}
+}
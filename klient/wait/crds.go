@@ -0,0 +1,31 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+)
+
+// ForCRDEstablished waits until the named CustomResourceDefinition is
+// established (see conditions.CRDEstablished), the wait times out, or
+// ctx is done.
+func ForCRDEstablished(ctx context.Context, client klient.Client, crdName string, opts ...Option) error {
+	return poll(ctx, conditions.New(client.Resources()).CRDEstablished(crdName), opts...)
+}
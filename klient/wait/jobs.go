@@ -0,0 +1,52 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+)
+
+// ForJobCompleted waits until the job completes (see conditions.JobCompleted),
+// the wait times out, or ctx is done.
+func ForJobCompleted(ctx context.Context, client klient.Client, job *batchv1.Job, opts ...Option) error {
+	return poll(ctx, conditions.New(client.Resources()).JobCompleted(job), opts...)
+}
+
+// ForJobFailed waits until the job fails (see conditions.JobFailed),
+// the wait times out, or ctx is done.
+func ForJobFailed(ctx context.Context, client klient.Client, job *batchv1.Job, opts ...Option) error {
+	return poll(ctx, conditions.New(client.Resources()).JobFailed(job), opts...)
+}
+
+// ForJobSucceededOrFailed waits until the job either completes or fails
+// (see conditions.JobCompleted and conditions.JobFailed), the wait times
+// out, or ctx is done. It reports whether the job succeeded.
+func ForJobSucceededOrFailed(ctx context.Context, client klient.Client, job *batchv1.Job, opts ...Option) (succeeded bool, err error) {
+	cond := conditions.New(client.Resources())
+	completed, failed := cond.JobCompleted(job), cond.JobFailed(job)
+	err = poll(ctx, func() (bool, error) {
+		if succeeded, err = completed(); err != nil || succeeded {
+			return succeeded, err
+		}
+		return failed()
+	}, opts...)
+	return succeeded, err
+}
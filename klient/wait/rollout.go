@@ -0,0 +1,45 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+)
+
+// ForRollout waits until the rollout of obj completes, the wait times out,
+// or ctx is done. The condition is chosen based on the type of obj:
+// conditions.DeploymentAvailable for a *appsv1.Deployment,
+// conditions.StatefulSetReady for a *appsv1.StatefulSet and
+// conditions.DaemonSetReady for a *appsv1.DaemonSet.
+func ForRollout(ctx context.Context, client klient.Client, obj k8s.Object, opts ...Option) error {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return poll(ctx, conditions.New(client.Resources()).DeploymentAvailable(o), opts...)
+	case *appsv1.StatefulSet:
+		return poll(ctx, conditions.New(client.Resources()).StatefulSetReady(o), opts...)
+	case *appsv1.DaemonSet:
+		return poll(ctx, conditions.New(client.Resources()).DaemonSetReady(o), opts...)
+	default:
+		return fmt.Errorf("wait for rollout: unsupported object type %T", obj)
+	}
+}
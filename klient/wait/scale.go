@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+)
+
+// ScaleAndWait scales obj, a *appsv1.Deployment, *appsv1.StatefulSet or
+// *appsv1.ReplicaSet, to replicas (see resources.Resources.Scale) then waits
+// until its status reports exactly replicas replicas, all of them ready (see
+// conditions.ResourceScaled), the wait times out, or ctx is done.
+func ScaleAndWait(ctx context.Context, client klient.Client, obj k8s.Object, replicas int32, opts ...Option) error {
+	if err := client.Resources().Scale(ctx, obj, replicas); err != nil {
+		return err
+	}
+
+	cond := conditions.New(client.Resources())
+	scaled := cond.ResourceScaled(obj, statusReplicas, replicas)
+	ready := cond.ResourceScaled(obj, readyReplicas, replicas)
+	return poll(ctx, func() (bool, error) {
+		if done, err := scaled(); err != nil || !done {
+			return done, err
+		}
+		return ready()
+	}, opts...)
+}
+
+// ForResourceScaled waits until scaleFunc returns target for obj (see
+// conditions.ResourceScaled), the wait times out, or ctx is done.
+func ForResourceScaled(ctx context.Context, client klient.Client, obj k8s.Object, scaleFunc func(k8s.Object) int32, target int32, opts ...Option) error {
+	return poll(ctx, conditions.New(client.Resources()).ResourceScaled(obj, scaleFunc, target), opts...)
+}
+
+// ScaleTo scales obj, a *appsv1.Deployment, *appsv1.StatefulSet or
+// *appsv1.ReplicaSet, to replicas (see resources.Resources.Scale) then waits
+// until scaleFunc returns replicas for obj (see ForResourceScaled), i.e. with
+// a scaleFunc returning the available replicas of a Deployment. Use
+// ScaleAndWait to wait for all the replicas to be ready.
+func ScaleTo(ctx context.Context, client klient.Client, obj k8s.Object, replicas int32, scaleFunc func(k8s.Object) int32, opts ...Option) error {
+	if err := client.Resources().Scale(ctx, obj, replicas); err != nil {
+		return err
+	}
+	return ForResourceScaled(ctx, client, obj, scaleFunc, replicas, opts...)
+}
+
+// statusReplicas returns the number of replicas in the status of obj.
+func statusReplicas(obj k8s.Object) int32 {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return o.Status.Replicas
+	case *appsv1.StatefulSet:
+		return o.Status.Replicas
+	case *appsv1.ReplicaSet:
+		return o.Status.Replicas
+	default:
+		panic(fmt.Sprintf("unsupported object type %T", obj))
+	}
+}
+
+// readyReplicas returns the number of ready replicas in the status of obj.
+func readyReplicas(obj k8s.Object) int32 {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return o.Status.ReadyReplicas
+	case *appsv1.StatefulSet:
+		return o.Status.ReadyReplicas
+	case *appsv1.ReplicaSet:
+		return o.Status.ReadyReplicas
+	default:
+		panic(fmt.Sprintf("unsupported object type %T", obj))
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wait provides functions to wait, by polling, for
+// a condition (see package conditions) to be satisfied.
+package wait
+
+import (
+	"context"
+	"time"
+
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultPollTimeout  = 5 * time.Minute
+)
+
+// PollFunc returns the interval to wait before the next poll,
+// given the time elapsed since the wait started.
+type PollFunc func(elapsed time.Duration) time.Duration
+
+// Options stores the values used to wait for a condition
+type Options struct {
+	// Interval is the fixed amount of time between polls
+	Interval time.Duration
+	// Timeout is the maximum amount of time to wait for the condition
+	Timeout time.Duration
+	// PollFunc, when set, replaces the fixed interval between polls
+	PollFunc PollFunc
+	// StabilityDuration is the amount of time the condition must remain
+	// continuously true before the wait succeeds
+	StabilityDuration time.Duration
+}
+
+// Option is used to configure how For waits for a condition
+type Option func(*Options)
+
+// WithInterval sets the fixed amount of time between polls
+func WithInterval(interval time.Duration) Option {
+	return func(o *Options) { o.Interval = interval }
+}
+
+// WithTimeout sets the maximum amount of time to wait for the condition
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) { o.Timeout = timeout }
+}
+
+// WithPollFunc sets a function that computes the interval before each
+// poll, which replaces the fixed interval. This allows adaptive polling
+// strategies such as ExponentialBackoffPollFn.
+func WithPollFunc(fn PollFunc) Option {
+	return func(o *Options) { o.PollFunc = fn }
+}
+
+// WithEventuallyConsistent requires the condition to remain continuously
+// true for d before the wait succeeds. The stability period restarts
+// whenever the condition becomes false, which guards against flapping
+// conditions.
+func WithEventuallyConsistent(d time.Duration) Option {
+	return func(o *Options) { o.StabilityDuration = d }
+}
+
+// ExponentialBackoffPollFn returns a PollFunc that starts with the initial
+// interval and multiplies the interval by multiplier after each poll, up to max.
+func ExponentialBackoffPollFn(initial, max time.Duration, multiplier float64) PollFunc {
+	return func(elapsed time.Duration) time.Duration {
+		// the sum of previous intervals i*m^0 + ... + i*m^(k-1) is the elapsed
+		// time, so the next interval i*m^k is i + elapsed*(m-1)
+		next := initial + time.Duration(float64(elapsed)*(multiplier-1))
+		if next > max || next < 0 {
+			return max
+		}
+		if next < initial {
+			return initial
+		}
+		return next
+	}
+}
+
+// For polls conditionFunc, starting immediately, until it returns true,
+// returns an error, or the timeout expires in which case ErrWaitTimeout
+// from package k8s.io/apimachinery/pkg/util/wait is returned.
+func For(conditionFunc apimachinerywait.ConditionFunc, opts ...Option) error {
+	return poll(context.Background(), conditionFunc, opts...)
+}
+
+// poll implements For and also stops waiting when ctx is done
+func poll(ctx context.Context, conditionFunc apimachinerywait.ConditionFunc, opts ...Option) error {
+	options := &Options{Interval: defaultPollInterval, Timeout: defaultPollTimeout}
+	for _, fn := range opts {
+		fn(options)
+	}
+
+	pollFn := options.PollFunc
+	if pollFn == nil {
+		pollFn = func(time.Duration) time.Duration { return options.Interval }
+	}
+
+	start := time.Now()
+	deadline := time.NewTimer(options.Timeout)
+	defer deadline.Stop()
+
+	var firstTrue time.Time
+	for {
+		done, err := conditionFunc()
+		if err != nil {
+			return err
+		}
+		switch {
+		case !done:
+			firstTrue = time.Time{}
+		case firstTrue.IsZero():
+			firstTrue = time.Now()
+		}
+		if done && time.Since(firstTrue) >= options.StabilityDuration {
+			return nil
+		}
+
+		next := time.NewTimer(pollFn(time.Since(start)))
+		select {
+		case <-ctx.Done():
+			next.Stop()
+			return ctx.Err()
+		case <-deadline.C:
+			next.Stop()
+			return apimachinerywait.ErrWaitTimeout
+		case <-next.C:
+		}
+	}
+}
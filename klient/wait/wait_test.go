@@ -0,0 +1,1450 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+)
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		doneAt   int
+		opts     []Option
+		expected error
+		calls    int
+	}{
+		{
+			name:   "done immediately",
+			doneAt: 1,
+			opts:   []Option{WithInterval(time.Millisecond), WithTimeout(time.Second)},
+			calls:  1,
+		},
+		{
+			name:   "done after polls",
+			doneAt: 3,
+			opts:   []Option{WithInterval(time.Millisecond), WithTimeout(time.Second)},
+			calls:  3,
+		},
+		{
+			name:     "timeout",
+			doneAt:   1000,
+			opts:     []Option{WithInterval(20 * time.Millisecond), WithTimeout(50 * time.Millisecond)},
+			expected: apimachinerywait.ErrWaitTimeout,
+		},
+		{
+			name:   "with poll func",
+			doneAt: 4,
+			opts: []Option{
+				WithInterval(time.Hour),
+				WithTimeout(time.Second),
+				WithPollFunc(ExponentialBackoffPollFn(time.Millisecond, 10*time.Millisecond, 2)),
+			},
+			calls: 4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			err := For(func() (bool, error) {
+				calls++
+				return calls >= test.doneAt, nil
+			}, test.opts...)
+			if !errors.Is(err, test.expected) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			// the number of calls is timing-dependent when timing out
+			if test.calls > 0 && calls != test.calls {
+				t.Errorf("unexpected number of calls: %d", calls)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffPollFn(t *testing.T) {
+	pollFn := ExponentialBackoffPollFn(time.Second, 10*time.Second, 2)
+
+	var elapsed time.Duration
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		interval := pollFn(elapsed)
+		if interval != expected {
+			t.Errorf("unexpected interval %s, expected %s", interval, expected)
+		}
+		elapsed += interval
+	}
+}
+
+func TestForRollout_UnsupportedType(t *testing.T) {
+	if err := ForRollout(context.TODO(), nil, &corev1.Pod{}); err == nil {
+		t.Error("expected an error for an object without rollout")
+	}
+}
+
+func TestFor_EventuallyConsistent(t *testing.T) {
+	opts := []Option{WithInterval(10 * time.Millisecond), WithTimeout(time.Second), WithEventuallyConsistent(50 * time.Millisecond)}
+
+	calls := 0
+	start := time.Now()
+	err := For(func() (bool, error) {
+		calls++
+		// flaps once before becoming stable
+		return calls != 2, nil
+	}, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("condition was not required to be stable, returned after %s", elapsed)
+	}
+
+	calls = 0
+	err = For(func() (bool, error) {
+		calls++
+		return calls%2 == 0, nil
+	}, opts[0], WithTimeout(200*time.Millisecond), opts[2])
+	if !errors.Is(err, apimachinerywait.ErrWaitTimeout) {
+		t.Errorf("expected timeout for a flapping condition, got %v", err)
+	}
+}
+
+// newCRDServer returns an API server serving only the v1beta1 version of
+// apiextensions.k8s.io, with the CRD crontabs.example.com established from
+// the nth request on. The CRD names are only accepted when namesAccepted is set.
+func newCRDServer(t *testing.T, establishedAt int, namesAccepted bool) *httptest.Server {
+	writeJSON := func(w http.ResponseWriter, obj interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(obj); err != nil {
+			t.Error(err)
+		}
+	}
+
+	calls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIVersions{Versions: []string{"v1"}})
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{GroupVersion: "v1"})
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, _ *http.Request) {
+		gv := metav1.GroupVersionForDiscovery{GroupVersion: "apiextensions.k8s.io/v1beta1", Version: "v1beta1"}
+		writeJSON(w, &metav1.APIGroupList{Groups: []metav1.APIGroup{
+			{Name: "apiextensions.k8s.io", Versions: []metav1.GroupVersionForDiscovery{gv}, PreferredVersion: gv},
+		}})
+	})
+	mux.HandleFunc("/apis/apiextensions.k8s.io/v1beta1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{
+			GroupVersion: "apiextensions.k8s.io/v1beta1",
+			APIResources: []metav1.APIResource{{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition", Verbs: metav1.Verbs{"get"}}},
+		})
+	})
+	mux.HandleFunc("/apis/apiextensions.k8s.io/v1beta1/customresourcedefinitions/crontabs.example.com", func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		status := func(ok bool) string {
+			if ok {
+				return "True"
+			}
+			return "False"
+		}
+		writeJSON(w, map[string]interface{}{
+			"apiVersion": "apiextensions.k8s.io/v1beta1",
+			"kind":       "CustomResourceDefinition",
+			"metadata":   map[string]interface{}{"name": "crontabs.example.com"},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "NamesAccepted", "status": status(namesAccepted)},
+					map[string]interface{}{"type": "Established", "status": status(calls >= establishedAt)},
+				},
+			},
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestForCRDEstablished(t *testing.T) {
+	server := newCRDServer(t, 3, true)
+	defer server.Close()
+
+	client, err := klient.New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ForCRDEstablished(context.TODO(), client, "crontabs.example.com", WithInterval(time.Millisecond), WithTimeout(time.Second))
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	err = ForCRDEstablished(context.TODO(), client, "missing.example.com", WithInterval(time.Millisecond), WithTimeout(50*time.Millisecond))
+	if !errors.Is(err, apimachinerywait.ErrWaitTimeout) {
+		t.Errorf("expected timeout waiting for a missing CRD, got %v", err)
+	}
+
+	conflicting := newCRDServer(t, 1, false)
+	defer conflicting.Close()
+
+	client, err = klient.New(&rest.Config{Host: conflicting.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ForCRDEstablished(context.TODO(), client, "crontabs.example.com", WithInterval(time.Millisecond), WithTimeout(50*time.Millisecond))
+	if !errors.Is(err, apimachinerywait.ErrWaitTimeout) {
+		t.Errorf("expected timeout waiting for a CRD with names not accepted, got %v", err)
+	}
+}
+
+func TestClusterVersionConditions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(&version.Info{Major: "1", Minor: "21+", GitVersion: "v1.21.1+k3s1"}); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := klient.New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond := conditions.New(client.Resources())
+
+	tests := []struct {
+		name     string
+		cond     apimachinerywait.ConditionFunc
+		expected bool
+	}{
+		{name: "at least older minor", cond: cond.ClusterVersionAtLeast(1, 20), expected: true},
+		{name: "at least same minor", cond: cond.ClusterVersionAtLeast(1, 21), expected: true},
+		{name: "at least newer minor", cond: cond.ClusterVersionAtLeast(1, 22)},
+		{name: "at least older major", cond: cond.ClusterVersionAtLeast(0, 30), expected: true},
+		{name: "exactly", cond: cond.ClusterVersionExactly(1, 21, 1), expected: true},
+		{name: "exactly other patch", cond: cond.ClusterVersionExactly(1, 21, 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			done, err := test.cond()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+}
+
+// newServiceServer returns a fake API server serving the Services "web" (ClusterIP),
+// "lb" (LoadBalancer) and "external" (ExternalName) in namespace "default". The
+// "lb" Service is only assigned an ingress IP when lbIP is set and "web" and "lb"
+// only get ready endpoints when endpointsReady is set.
+func newServiceServer(t *testing.T, lbIP string, endpointsReady bool) *httptest.Server {
+	writeJSON := func(w http.ResponseWriter, obj interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(obj); err != nil {
+			t.Error(err)
+		}
+	}
+	service := func(name string, spec corev1.ServiceSpec, status corev1.ServiceStatus) *corev1.Service {
+		return &corev1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       spec,
+			Status:     status,
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIVersions{Versions: []string{"v1"}})
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "services", Namespaced: true, Kind: "Service", Verbs: metav1.Verbs{"get"}}},
+		})
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, _ *http.Request) {
+		gv := metav1.GroupVersionForDiscovery{GroupVersion: "discovery.k8s.io/v1", Version: "v1"}
+		writeJSON(w, &metav1.APIGroupList{Groups: []metav1.APIGroup{
+			{Name: "discovery.k8s.io", Versions: []metav1.GroupVersionForDiscovery{gv}, PreferredVersion: gv},
+		}})
+	})
+	mux.HandleFunc("/apis/discovery.k8s.io/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{
+			GroupVersion: "discovery.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "endpointslices", Namespaced: true, Kind: "EndpointSlice", Verbs: metav1.Verbs{"list"}}},
+		})
+	})
+	mux.HandleFunc("/apis/discovery.k8s.io/v1/namespaces/default/endpointslices", func(w http.ResponseWriter, _ *http.Request) {
+		list := &discoveryv1.EndpointSliceList{TypeMeta: metav1.TypeMeta{APIVersion: "discovery.k8s.io/v1", Kind: "EndpointSliceList"}}
+		if endpointsReady {
+			ready := true
+			list.Items = append(list.Items, discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{Name: "slice", Namespace: "default"},
+				Endpoints:  []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}}},
+			})
+		}
+		writeJSON(w, list)
+	})
+	selector := map[string]string{"app": "web"}
+	mux.HandleFunc("/api/v1/namespaces/default/services/web", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, service("web", corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP, Selector: selector}, corev1.ServiceStatus{}))
+	})
+	mux.HandleFunc("/api/v1/namespaces/default/services/lb", func(w http.ResponseWriter, _ *http.Request) {
+		var status corev1.ServiceStatus
+		if lbIP != "" {
+			status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: lbIP}}
+		}
+		writeJSON(w, service("lb", corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Selector: selector}, status))
+	})
+	mux.HandleFunc("/api/v1/namespaces/default/services/external", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, service("external", corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "example.com"}, corev1.ServiceStatus{}))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestServiceConditions(t *testing.T) {
+	externalIPAssigned := (*conditions.Condition).ServiceExternalIPAssigned
+	ready := (*conditions.Condition).ServiceReady
+
+	tests := []struct {
+		name           string
+		service        string
+		lbIP           string
+		endpointsReady bool
+		cond           func(*conditions.Condition, *corev1.Service) apimachinerywait.ConditionFunc
+		expected       bool
+	}{
+		{name: "external ip pending", service: "lb", cond: externalIPAssigned},
+		{name: "external ip assigned", service: "lb", lbIP: "192.0.2.1", cond: externalIPAssigned, expected: true},
+		{name: "cluster ip without endpoints", service: "web", cond: ready},
+		{name: "cluster ip with endpoints", service: "web", endpointsReady: true, cond: ready, expected: true},
+		{name: "load balancer without ip", service: "lb", endpointsReady: true, cond: ready},
+		{name: "load balancer without endpoints", service: "lb", lbIP: "192.0.2.1", cond: ready},
+		{name: "load balancer ready", service: "lb", lbIP: "192.0.2.1", endpointsReady: true, cond: ready, expected: true},
+		{name: "external name", service: "external", cond: ready, expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newServiceServer(t, test.lbIP, test.endpointsReady)
+			defer server.Close()
+
+			client, err := klient.New(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: test.service, Namespace: "default"}}
+			done, err := test.cond(conditions.New(client.Resources()), svc)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+}
+
+func TestAllPodsInNamespaceTerminated(t *testing.T) {
+	remaining := 2
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&metav1.APIVersions{Versions: []string{"v1"}})
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&metav1.APIResourceList{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"list"}}},
+		})
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&metav1.APIGroupList{})
+	})
+	mux.HandleFunc("/api/v1/namespaces/test-ns/pods", func(w http.ResponseWriter, _ *http.Request) {
+		// one pod terminates with each list call
+		list := &corev1.PodList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"}}
+		for i := 0; i < remaining; i++ {
+			list.Items = append(list.Items, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: "test-ns"}})
+		}
+		if remaining > 0 {
+			remaining--
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := klient.New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cond := conditions.New(client.Resources()).AllPodsInNamespaceTerminated("test-ns")
+	done, err := cond()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done {
+		t.Error("expected pods to be remaining")
+	}
+
+	if err := For(cond, WithInterval(time.Millisecond), WithTimeout(time.Second)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if remaining != 0 {
+		t.Errorf("expected all pods to be terminated, %d remaining", remaining)
+	}
+}
+
+// newIngressServer returns a fake API server serving the Ingress "web" in namespace
+// "default", with rules for hosts foo.example.com (paths /app and /api, unless
+// paths are given) and bar.example.com, and the address ip once it is set.
+func newIngressServer(t *testing.T, ip string, paths ...string) *httptest.Server {
+	if len(paths) == 0 {
+		paths = []string{"/app", "/api"}
+	}
+	var fooPaths []networkingv1.HTTPIngressPath
+	for _, path := range paths {
+		fooPaths = append(fooPaths, networkingv1.HTTPIngressPath{Path: path})
+	}
+
+	writeJSON := func(w http.ResponseWriter, obj interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(obj); err != nil {
+			t.Error(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIVersions{Versions: []string{"v1"}})
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{GroupVersion: "v1"})
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, _ *http.Request) {
+		gv := metav1.GroupVersionForDiscovery{GroupVersion: "networking.k8s.io/v1", Version: "v1"}
+		writeJSON(w, &metav1.APIGroupList{Groups: []metav1.APIGroup{
+			{Name: "networking.k8s.io", Versions: []metav1.GroupVersionForDiscovery{gv}, PreferredVersion: gv},
+		}})
+	})
+	mux.HandleFunc("/apis/networking.k8s.io/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{
+			GroupVersion: "networking.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "ingresses", Namespaced: true, Kind: "Ingress", Verbs: metav1.Verbs{"get"}}},
+		})
+	})
+	mux.HandleFunc("/apis/networking.k8s.io/v1/namespaces/default/ingresses/web", func(w http.ResponseWriter, _ *http.Request) {
+		ing := &networkingv1.Ingress{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+				{
+					Host: "foo.example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: fooPaths,
+					}},
+				},
+				{Host: "bar.example.com"},
+			}},
+		}
+		if ip != "" {
+			ing.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: ip}}
+		}
+		writeJSON(w, ing)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestIngressConditions(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		requested []string
+		failing   = "bar.example.com/"
+	)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requested = append(requested, r.Host+r.URL.Path)
+		if r.Host+r.URL.Path == failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer backend.Close()
+
+	// send all requests to the backend, whatever the ingress address
+	httpClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, backend.Listener.Addr().String())
+		},
+	}}
+
+	addressAssigned := (*conditions.Condition).IngressAddressAssigned
+	available := func(c *conditions.Condition, ing *networkingv1.Ingress) apimachinerywait.ConditionFunc {
+		return c.IngressAvailable(ing, httpClient)
+	}
+
+	tests := []struct {
+		name     string
+		ip       string
+		cond     func(*conditions.Condition, *networkingv1.Ingress) apimachinerywait.ConditionFunc
+		expected bool
+	}{
+		{name: "address pending", cond: addressAssigned},
+		{name: "address assigned", ip: "192.0.2.1", cond: addressAssigned, expected: true},
+		{name: "not available without address", cond: available},
+		{name: "not available with a failing rule", ip: "192.0.2.1", cond: available},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newIngressServer(t, test.ip)
+			defer server.Close()
+
+			client, err := klient.New(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ing := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+			done, err := test.cond(conditions.New(client.Resources()), ing)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+
+	t.Run("available", func(t *testing.T) {
+		mu.Lock()
+		failing = ""
+		requested = nil
+		mu.Unlock()
+
+		server := newIngressServer(t, "192.0.2.1")
+		defer server.Close()
+
+		client, err := klient.New(&rest.Config{Host: server.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ing := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		done, err := conditions.New(client.Resources()).IngressAvailable(ing, httpClient)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !done {
+			t.Error("expected ingress to be available")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		expected := []string{"foo.example.com/app", "foo.example.com/api", "bar.example.com/"}
+		if strings.Join(requested, ",") != strings.Join(expected, ",") {
+			t.Errorf("expected requests %v, got %v", expected, requested)
+		}
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		server := newIngressServer(t, "192.0.2.1", "/app", "/%zz")
+		defer server.Close()
+
+		client, err := klient.New(&rest.Config{Host: server.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the rule that cannot be requested is reported, not skipped
+		ing := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		done, err := conditions.New(client.Resources()).IngressAvailable(ing, httpClient)()
+		if err == nil || done {
+			t.Errorf("expected an error for the invalid path, got done=%t, err=%v", done, err)
+		}
+	})
+}
+
+// newScaleServer returns a fake API server serving the Deployment "web" in
+// namespace "default", with 2 ready replicas. Once scaled down, it loses a
+// ready replica, then a replica, with each get. The last scale patch is
+// stored in patch.
+func newScaleServer(t *testing.T, patch *string) *httptest.Server {
+	writeJSON := func(w http.ResponseWriter, obj interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(obj); err != nil {
+			t.Error(err)
+		}
+	}
+
+	replicas, ready := int32(2), int32(2)
+	scaled := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIVersions{Versions: []string{"v1"}})
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{GroupVersion: "v1"})
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, _ *http.Request) {
+		gv := metav1.GroupVersionForDiscovery{GroupVersion: "apps/v1", Version: "v1"}
+		writeJSON(w, &metav1.APIGroupList{Groups: []metav1.APIGroup{
+			{Name: "apps", Versions: []metav1.GroupVersionForDiscovery{gv}, PreferredVersion: gv},
+		}})
+	})
+	mux.HandleFunc("/apis/apps/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: metav1.Verbs{"get", "patch"}}},
+		})
+	})
+	mux.HandleFunc("/apis/apps/v1/namespaces/default/deployments/web/scale", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		*patch = string(body)
+		scaled = true
+		writeJSON(w, &autoscalingv1.Scale{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "autoscaling/v1", Kind: "Scale"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		})
+	})
+	mux.HandleFunc("/apis/apps/v1/namespaces/default/deployments/web", func(w http.ResponseWriter, _ *http.Request) {
+		// zero replicas are written, as the JSON of a Deployment omits them
+		// and the client decodes into the object of the previous get
+		dep := map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+			"status":     map[string]interface{}{"replicas": replicas, "readyReplicas": ready},
+		}
+		if scaled && ready > 0 {
+			ready--
+		} else if scaled && replicas > 0 {
+			replicas--
+		}
+		writeJSON(w, dep)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestScaleAndWait(t *testing.T) {
+	var patch string
+	server := newScaleServer(t, &patch)
+	defer server.Close()
+
+	client, err := klient.New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	if err := ScaleAndWait(context.TODO(), client, dep, 0, WithInterval(time.Millisecond), WithTimeout(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if patch != `{"spec":{"replicas":0}}` {
+		t.Errorf("unexpected scale patch %s", patch)
+	}
+	if dep.Status.ReadyReplicas != 0 || dep.Status.Replicas != 0 {
+		t.Errorf("expected the deployment to be scaled to 0, got %d replicas and %d ready", dep.Status.Replicas, dep.Status.ReadyReplicas)
+	}
+
+	err = ScaleAndWait(context.TODO(), client, &appsv1.DaemonSet{}, 0)
+	if err == nil {
+		t.Error("expected an error scaling a DaemonSet")
+	}
+}
+
+func TestScaleTo(t *testing.T) {
+	var patch string
+	server := newScaleServer(t, &patch)
+	defer server.Close()
+
+	client, err := klient.New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readyReplicas := func(obj k8s.Object) int32 {
+		return obj.(*appsv1.Deployment).Status.ReadyReplicas
+	}
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+
+	err = ForResourceScaled(context.TODO(), client, dep, readyReplicas, 0, WithInterval(time.Millisecond), WithTimeout(50*time.Millisecond))
+	if !errors.Is(err, apimachinerywait.ErrWaitTimeout) {
+		t.Errorf("expected timeout waiting for a deployment that is not scaled, got %v", err)
+	}
+
+	if err := ScaleTo(context.TODO(), client, dep, 0, readyReplicas, WithInterval(time.Millisecond), WithTimeout(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if patch != `{"spec":{"replicas":0}}` {
+		t.Errorf("unexpected scale patch %s", patch)
+	}
+	if dep.Status.ReadyReplicas != 0 {
+		t.Errorf("expected no ready replicas, got %d", dep.Status.ReadyReplicas)
+	}
+}
+
+func TestHelmReleaseDeployed(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		exitCode   int
+		expected   bool
+		shouldFail bool
+	}{
+		{name: "no release", output: "Error: release: not found", exitCode: 1},
+		{name: "pending install", output: `{"name":"nginx","info":{"status":"pending-install"},"version":1}`},
+		{name: "deployed", output: `{"name":"nginx","info":{"status":"deployed"},"version":1}`, expected: true},
+		{name: "pending upgrade", output: `{"name":"nginx","info":{"status":"pending-upgrade"},"version":2}`},
+		{name: "cluster unreachable", output: "Error: Kubernetes cluster unreachable", exitCode: 1, shouldFail: true},
+		{name: "invalid output", output: "deployed", shouldFail: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// a fake helm binary, first in PATH, that prints the output for the expected arguments
+			bin := t.TempDir()
+			script := fmt.Sprintf("#!/bin/sh\n"+
+				"if [ \"$*\" != \"status nginx -n web -o json\" ]; then echo \"unexpected arguments $*\"; exit 2; fi\n"+
+				"echo '%s'\nexit %d\n", test.output, test.exitCode)
+			if err := ioutil.WriteFile(filepath.Join(bin, "helm"), []byte(script), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			path := os.Getenv("PATH")
+			os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+			defer os.Setenv("PATH", path)
+
+			done, err := conditions.New(nil).HelmReleaseDeployed("web", "nginx")()
+			if test.shouldFail {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+}
+
+// newObjectServer returns a fake API server serving the discovery of resource, in
+// groupVersion, and the objects by request path. An object that is a
+// http.HandlerFunc handles the requests of its path.
+func newObjectServer(t *testing.T, groupVersion string, resource metav1.APIResource, objects map[string]interface{}) *httptest.Server {
+	writeJSON := func(w http.ResponseWriter, obj interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(obj); err != nil {
+			t.Error(err)
+		}
+	}
+	gv, err := schema.ParseGroupVersion(groupVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources := &metav1.APIResourceList{GroupVersion: groupVersion, APIResources: []metav1.APIResource{resource}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIVersions{Versions: []string{"v1"}})
+	})
+	groups := &metav1.APIGroupList{}
+	if gv.Group == "" {
+		mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, resources)
+		})
+	} else {
+		version := metav1.GroupVersionForDiscovery{GroupVersion: groupVersion, Version: gv.Version}
+		groups.Groups = append(groups.Groups, metav1.APIGroup{Name: gv.Group, Versions: []metav1.GroupVersionForDiscovery{version}, PreferredVersion: version})
+		mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, &metav1.APIResourceList{GroupVersion: "v1"})
+		})
+		mux.HandleFunc("/apis/"+groupVersion, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, resources)
+		})
+	}
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, groups)
+	})
+	for path, obj := range objects {
+		if handler, ok := obj.(http.HandlerFunc); ok {
+			mux.HandleFunc(path, handler)
+			continue
+		}
+		obj := obj
+		mux.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, obj)
+		})
+	}
+	return httptest.NewServer(mux)
+}
+
+// newObjectClient returns a client of a fake API server created with newObjectServer.
+func newObjectClient(t *testing.T, groupVersion string, resource metav1.APIResource, objects map[string]interface{}) klient.Client {
+	server := newObjectServer(t, groupVersion, resource, objects)
+	t.Cleanup(server.Close)
+
+	// request bodies are sent as JSON so fake handlers can decode them
+	client, err := klient.New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestPVCCapacityAtLeast(t *testing.T) {
+	pvcResource := metav1.APIResource{Name: "persistentvolumeclaims", Namespaced: true, Kind: "PersistentVolumeClaim", Verbs: metav1.Verbs{"get"}}
+	tests := []struct {
+		name     string
+		capacity string
+		size     string
+		expected bool
+	}{
+		{name: "no capacity", size: "1Gi"},
+		{name: "smaller", capacity: "1Gi", size: "2Gi"},
+		{name: "equal", capacity: "2Gi", size: "2Gi", expected: true},
+		{name: "equal in other unit", capacity: "1024Mi", size: "1Gi", expected: true},
+		{name: "larger", capacity: "5Gi", size: "2Gi", expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pvc := &corev1.PersistentVolumeClaim{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+				ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+				Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+			}
+			if test.capacity != "" {
+				pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(test.capacity)}
+			}
+			client := newObjectClient(t, "v1", pvcResource, map[string]interface{}{
+				"/api/v1/namespaces/default/persistentvolumeclaims/data": pvc,
+			})
+
+			got := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"}}
+			done, err := conditions.New(client.Resources()).PVCCapacityAtLeast(got, resource.MustParse(test.size))()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		client := newObjectClient(t, "v1", pvcResource, nil)
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"}}
+		if _, err := conditions.New(client.Resources()).PVCCapacityAtLeast(pvc, resource.MustParse("1Gi"))(); err == nil {
+			t.Error("expected an error for a missing claim")
+		}
+	})
+}
+
+func TestEndpointSliceReady(t *testing.T) {
+	ready, notReady := true, false
+	endpoint := func(ip string, ready *bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{Addresses: []string{ip}, Conditions: discoveryv1.EndpointConditions{Ready: ready}}
+	}
+	slices := &discoveryv1.EndpointSliceList{
+		TypeMeta: metav1.TypeMeta{APIVersion: "discovery.k8s.io/v1", Kind: "EndpointSliceList"},
+		Items: []discoveryv1.EndpointSlice{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-a", Namespace: "default"},
+				Endpoints:  []discoveryv1.Endpoint{endpoint("10.0.0.1", &ready), endpoint("10.0.0.2", &notReady)},
+			},
+			{
+				// a nil Ready condition is counted as ready
+				ObjectMeta: metav1.ObjectMeta{Name: "web-b", Namespace: "default"},
+				Endpoints:  []discoveryv1.Endpoint{endpoint("10.0.0.3", nil)},
+			},
+		},
+	}
+	client := newObjectClient(t, "discovery.k8s.io/v1",
+		metav1.APIResource{Name: "endpointslices", Namespaced: true, Kind: "EndpointSlice", Verbs: metav1.Verbs{"list"}},
+		map[string]interface{}{
+			"/apis/discovery.k8s.io/v1/namespaces/default/endpointslices": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				list := &discoveryv1.EndpointSliceList{TypeMeta: slices.TypeMeta}
+				if selector := r.URL.Query().Get("labelSelector"); selector == discoveryv1.LabelServiceName+"=web" {
+					list = slices
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(list)
+			}),
+		},
+	)
+
+	tests := []struct {
+		name         string
+		service      string
+		minEndpoints int
+		expected     bool
+	}{
+		{name: "single ready endpoint", service: "web", minEndpoints: 1, expected: true},
+		{name: "ready endpoints across slices", service: "web", minEndpoints: 2, expected: true},
+		{name: "not ready endpoint not counted", service: "web", minEndpoints: 3},
+		{name: "other service", service: "api", minEndpoints: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			done, err := conditions.New(client.Resources()).EndpointSliceReady(test.service, "default", test.minEndpoints)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+}
+
+func TestPersistentVolumeConditions(t *testing.T) {
+	client := newObjectClient(t, "v1",
+		metav1.APIResource{Name: "persistentvolumes", Kind: "PersistentVolume", Verbs: metav1.Verbs{"get"}},
+		map[string]interface{}{
+			"/api/v1/persistentvolumes/pv-1": &corev1.PersistentVolume{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"},
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+				Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain},
+				Status:     corev1.PersistentVolumeStatus{Phase: corev1.VolumeReleased},
+			},
+		},
+	)
+	reclaimPolicy := func(policy corev1.PersistentVolumeReclaimPolicy) func(*conditions.Condition, *corev1.PersistentVolume) apimachinerywait.ConditionFunc {
+		return func(c *conditions.Condition, pv *corev1.PersistentVolume) apimachinerywait.ConditionFunc {
+			return c.PersistentVolumeReclaimPolicyIs(pv, policy)
+		}
+	}
+	phase := func(phase corev1.PersistentVolumePhase) func(*conditions.Condition, *corev1.PersistentVolume) apimachinerywait.ConditionFunc {
+		return func(c *conditions.Condition, pv *corev1.PersistentVolume) apimachinerywait.ConditionFunc {
+			return c.PVStatusPhase(pv, phase)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		pv         string
+		cond       func(*conditions.Condition, *corev1.PersistentVolume) apimachinerywait.ConditionFunc
+		expected   bool
+		shouldFail bool
+	}{
+		{name: "reclaim policy matches", pv: "pv-1", cond: reclaimPolicy(corev1.PersistentVolumeReclaimRetain), expected: true},
+		{name: "reclaim policy differs", pv: "pv-1", cond: reclaimPolicy(corev1.PersistentVolumeReclaimDelete)},
+		{name: "reclaim policy of missing volume", pv: "pv-2", cond: reclaimPolicy(corev1.PersistentVolumeReclaimRetain), shouldFail: true},
+		{name: "phase matches", pv: "pv-1", cond: phase(corev1.VolumeReleased), expected: true},
+		{name: "phase differs", pv: "pv-1", cond: phase(corev1.VolumeBound)},
+		{name: "phase of missing volume", pv: "pv-2", cond: phase(corev1.VolumeReleased), shouldFail: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pv := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: test.pv}}
+			done, err := test.cond(conditions.New(client.Resources()), pv)()
+			if test.shouldFail {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+}
+
+func TestPodInitContainerConditions(t *testing.T) {
+	initStatus := func(name string, state corev1.ContainerState) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: state}
+	}
+	client := newObjectClient(t, "v1",
+		metav1.APIResource{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"get"}},
+		map[string]interface{}{
+			"/api/v1/namespaces/default/pods/web": &corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+					initStatus("migrate", corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}),
+					initStatus("seed", corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}}),
+					initStatus("warmup", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}),
+				}},
+			},
+		},
+	)
+	terminated := (*conditions.Condition).PodInitContainerTerminated
+	succeeded := (*conditions.Condition).PodInitContainerSucceeded
+
+	tests := []struct {
+		name       string
+		pod        string
+		container  string
+		cond       func(*conditions.Condition, *corev1.Pod, string) apimachinerywait.ConditionFunc
+		expected   bool
+		shouldFail bool
+	}{
+		{name: "terminated with success", pod: "web", container: "migrate", cond: terminated, expected: true},
+		{name: "terminated with failure", pod: "web", container: "seed", cond: terminated, expected: true},
+		{name: "running", pod: "web", container: "warmup", cond: terminated},
+		{name: "not started", pod: "web", container: "missing", cond: terminated},
+		{name: "succeeded", pod: "web", container: "migrate", cond: succeeded, expected: true},
+		{name: "failed", pod: "web", container: "seed", cond: succeeded},
+		{name: "running not succeeded", pod: "web", container: "warmup", cond: succeeded},
+		{name: "missing pod", pod: "api", container: "migrate", cond: terminated, shouldFail: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: test.pod, Namespace: "default"}}
+			done, err := test.cond(conditions.New(client.Resources()), pod, test.container)()
+			if test.shouldFail {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+}
+
+func TestObjectChangedConditions(t *testing.T) {
+	// newClient returns a client of an API server that returns the config map
+	// as updated once more on each request
+	newClient := func(t *testing.T) klient.Client {
+		var mu sync.Mutex
+		updates := 0
+		return newObjectClient(t, "v1",
+			metav1.APIResource{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"get"}},
+			map[string]interface{}{
+				"/api/v1/namespaces/default/configmaps/settings": http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					mu.Lock()
+					updates++
+					version := updates
+					mu.Unlock()
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(&corev1.ConfigMap{
+						TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+						ObjectMeta: metav1.ObjectMeta{
+							Name:            "settings",
+							Namespace:       "default",
+							ResourceVersion: fmt.Sprint(version),
+							Generation:      int64(version),
+						},
+					})
+				}),
+			},
+		)
+	}
+	resourceVersionChanged := func(c *conditions.Condition, obj k8s.Object) apimachinerywait.ConditionFunc {
+		return c.ResourceVersionChanged(obj, "1")
+	}
+	generationChanged := func(c *conditions.Condition, obj k8s.Object) apimachinerywait.ConditionFunc {
+		return c.GenerationChanged(obj, 1)
+	}
+
+	tests := []struct {
+		name string
+		cond func(*conditions.Condition, k8s.Object) apimachinerywait.ConditionFunc
+	}{
+		{name: "resource version", cond: resourceVersionChanged},
+		{name: "generation", cond: generationChanged},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := conditions.New(newClient(t).Resources())
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}}
+			// the object is at the original version on the first call and updated afterwards
+			for i, expected := range []bool{false, true, true} {
+				done, err := test.cond(c, cm)()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if done != expected {
+					t.Errorf("call %d: expected %t, got %t", i+1, expected, done)
+				}
+			}
+
+			missing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "default"}}
+			if _, err := test.cond(c, missing)(); err == nil {
+				t.Error("expected an error for a missing object")
+			}
+		})
+	}
+}
+
+// newDaemonSetClient returns a client of an API server serving the DaemonSet
+// "agent" in namespace "default", with numberReady of desired pods ready.
+func newDaemonSetClient(t *testing.T, numberReady, desired int32) klient.Client {
+	return newObjectClient(t, "apps/v1",
+		metav1.APIResource{Name: "daemonsets", Namespaced: true, Kind: "DaemonSet", Verbs: metav1.Verbs{"get"}},
+		map[string]interface{}{
+			"/apis/apps/v1/namespaces/default/daemonsets/agent": &appsv1.DaemonSet{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"},
+				ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+				Status:     appsv1.DaemonSetStatus{NumberReady: numberReady, DesiredNumberScheduled: desired},
+			},
+		},
+	)
+}
+
+func TestDaemonSetConditions(t *testing.T) {
+	ready := (*conditions.Condition).DaemonSetReady
+	minimumReady := func(fraction float64) func(*conditions.Condition, *appsv1.DaemonSet) apimachinerywait.ConditionFunc {
+		return func(c *conditions.Condition, ds *appsv1.DaemonSet) apimachinerywait.ConditionFunc {
+			return c.DaemonSetMinimumReady(ds, fraction)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		numberReady int32
+		desired     int32
+		cond        func(*conditions.Condition, *appsv1.DaemonSet) apimachinerywait.ConditionFunc
+		expected    bool
+	}{
+		{name: "all ready", numberReady: 3, desired: 3, cond: ready, expected: true},
+		{name: "partially ready", numberReady: 2, desired: 3, cond: ready},
+		{name: "none ready", numberReady: 0, desired: 3, cond: ready},
+		{name: "no nodes", cond: ready, expected: true},
+		{name: "minimum reached", numberReady: 4, desired: 5, cond: minimumReady(0.8), expected: true},
+		{name: "minimum exceeded", numberReady: 5, desired: 5, cond: minimumReady(0.8), expected: true},
+		{name: "minimum not reached", numberReady: 3, desired: 5, cond: minimumReady(0.8)},
+		{name: "minimum with no nodes", cond: minimumReady(0.8), expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newDaemonSetClient(t, test.numberReady, test.desired)
+			ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"}}
+			done, err := test.cond(conditions.New(client.Resources()), ds)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+}
+
+func TestForRollout(t *testing.T) {
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+	}
+	statefulSet := &appsv1.StatefulSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 1},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{ObservedGeneration: 1, UpdatedReplicas: 2, ReadyReplicas: 2, CurrentRevision: "db-1", UpdateRevision: "db-1"},
+	}
+	opts := []Option{WithInterval(10 * time.Millisecond), WithTimeout(100 * time.Millisecond)}
+
+	t.Run("deployment", func(t *testing.T) {
+		client := newObjectClient(t, "apps/v1",
+			metav1.APIResource{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: metav1.Verbs{"get"}},
+			map[string]interface{}{"/apis/apps/v1/namespaces/default/deployments/web": deployment},
+		)
+		obj := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		if err := ForRollout(context.TODO(), client, obj, opts...); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("stateful set", func(t *testing.T) {
+		client := newObjectClient(t, "apps/v1",
+			metav1.APIResource{Name: "statefulsets", Namespaced: true, Kind: "StatefulSet", Verbs: metav1.Verbs{"get"}},
+			map[string]interface{}{"/apis/apps/v1/namespaces/default/statefulsets/db": statefulSet},
+		)
+		obj := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}
+		if err := ForRollout(context.TODO(), client, obj, opts...); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("daemon set ready", func(t *testing.T) {
+		obj := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"}}
+		if err := ForRollout(context.TODO(), newDaemonSetClient(t, 3, 3), obj, opts...); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("daemon set not ready", func(t *testing.T) {
+		obj := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"}}
+		if err := ForRollout(context.TODO(), newDaemonSetClient(t, 1, 3), obj, opts...); err == nil {
+			t.Error("expected the rollout to time out")
+		}
+	})
+}
+
+func TestForJobSucceededOrFailed(t *testing.T) {
+	backoffLimit := int32(1)
+	tests := []struct {
+		name       string
+		status     batchv1.JobStatus
+		succeeded  bool
+		shouldFail bool
+	}{
+		{name: "succeeded", status: batchv1.JobStatus{Succeeded: 1}, succeeded: true},
+		{name: "succeeded after a failure", status: batchv1.JobStatus{Succeeded: 1, Failed: 1}, succeeded: true},
+		{name: "failed", status: batchv1.JobStatus{Failed: 2}},
+		{name: "running", status: batchv1.JobStatus{Active: 1, Failed: 1}, shouldFail: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newObjectClient(t, "batch/v1",
+				metav1.APIResource{Name: "jobs", Namespaced: true, Kind: "Job", Verbs: metav1.Verbs{"get"}},
+				map[string]interface{}{
+					"/apis/batch/v1/namespaces/default/jobs/migrate": &batchv1.Job{
+						TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+						ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default"},
+						Spec:       batchv1.JobSpec{BackoffLimit: &backoffLimit},
+						Status:     test.status,
+					},
+				},
+			)
+
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default"}}
+			succeeded, err := ForJobSucceededOrFailed(context.TODO(), client, job, WithInterval(10*time.Millisecond), WithTimeout(100*time.Millisecond))
+			if test.shouldFail {
+				if err == nil {
+					t.Error("expected the wait to time out")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if succeeded != test.succeeded {
+				t.Errorf("expected succeeded %t, got %t", test.succeeded, succeeded)
+			}
+		})
+	}
+}
+
+func TestPersistentVolumeClaimBound(t *testing.T) {
+	for _, phase := range []corev1.PersistentVolumeClaimPhase{corev1.ClaimPending, corev1.ClaimBound, corev1.ClaimLost} {
+		t.Run(string(phase), func(t *testing.T) {
+			client := newObjectClient(t, "v1",
+				metav1.APIResource{Name: "persistentvolumeclaims", Namespaced: true, Kind: "PersistentVolumeClaim", Verbs: metav1.Verbs{"get"}},
+				map[string]interface{}{
+					"/api/v1/namespaces/default/persistentvolumeclaims/data": &corev1.PersistentVolumeClaim{
+						TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+						ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+						Status:     corev1.PersistentVolumeClaimStatus{Phase: phase},
+					},
+				},
+			)
+
+			pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"}}
+			done, err := conditions.New(client.Resources()).PersistentVolumeClaimBound(pvc)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := phase == corev1.ClaimBound; done != expected {
+				t.Errorf("expected %t, got %t", expected, done)
+			}
+		})
+	}
+}
+
+func TestPersistentVolumeAvailable(t *testing.T) {
+	for _, phase := range []corev1.PersistentVolumePhase{corev1.VolumePending, corev1.VolumeAvailable, corev1.VolumeBound, corev1.VolumeReleased} {
+		t.Run(string(phase), func(t *testing.T) {
+			client := newObjectClient(t, "v1",
+				metav1.APIResource{Name: "persistentvolumes", Kind: "PersistentVolume", Verbs: metav1.Verbs{"get"}},
+				map[string]interface{}{
+					"/api/v1/persistentvolumes/pv-1": &corev1.PersistentVolume{
+						TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"},
+						ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+						Status:     corev1.PersistentVolumeStatus{Phase: phase},
+					},
+				},
+			)
+
+			pv := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1"}}
+			done, err := conditions.New(client.Resources()).PersistentVolumeAvailable(pv)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := phase == corev1.VolumeAvailable; done != expected {
+				t.Errorf("expected %t, got %t", expected, done)
+			}
+		})
+	}
+}
+
+func TestAllPodsReady(t *testing.T) {
+	pod := func(name, app string, containersReady corev1.ConditionStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": app}},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+				{Type: corev1.ContainersReady, Status: containersReady},
+			}},
+		}
+	}
+	pods := []corev1.Pod{
+		pod("web-0", "web", corev1.ConditionTrue),
+		pod("web-1", "web", corev1.ConditionTrue),
+		pod("api-0", "api", corev1.ConditionTrue),
+		pod("api-1", "api", corev1.ConditionFalse),
+		{ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default", Labels: map[string]string{"app": "worker"}}},
+	}
+	client := newObjectClient(t, "v1",
+		metav1.APIResource{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"list"}},
+		map[string]interface{}{
+			"/api/v1/namespaces/default/pods": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				list := &corev1.PodList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"}}
+				for _, pod := range pods {
+					if r.URL.Query().Get("labelSelector") == "app="+pod.Labels["app"] {
+						list.Items = append(list.Items, pod)
+					}
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(list)
+			}),
+		},
+	)
+
+	tests := []struct {
+		name     string
+		selector string
+		expected bool
+	}{
+		{name: "all ready", selector: "app=web", expected: true},
+		{name: "one not ready", selector: "app=api"},
+		{name: "condition not set", selector: "app=worker"},
+		{name: "no pods", selector: "app=missing"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			done, err := conditions.New(client.Resources()).AllPodsReady("default", test.selector)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+}
+
+func TestTokenReviewSucceeds(t *testing.T) {
+	client := newObjectClient(t, "authentication.k8s.io/v1",
+		metav1.APIResource{Name: "tokenreviews", Kind: "TokenReview", Verbs: metav1.Verbs{"create"}},
+		map[string]interface{}{
+			// only the token "valid", for audience "api", is authenticated
+			"/apis/authentication.k8s.io/v1/tokenreviews": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("unexpected method %s", r.Method)
+				}
+				review := &authenticationv1.TokenReview{}
+				if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+					t.Error(err)
+				}
+				if review.Spec.Token == "valid" {
+					for _, audience := range review.Spec.Audiences {
+						if audience == "api" {
+							review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, Audiences: []string{audience}}
+						}
+					}
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				_ = json.NewEncoder(w).Encode(review)
+			}),
+		},
+	)
+
+	tests := []struct {
+		name      string
+		token     string
+		audiences []string
+		expected  bool
+	}{
+		{name: "authenticated", token: "valid", audiences: []string{"api"}, expected: true},
+		{name: "invalid token", token: "invalid", audiences: []string{"api"}},
+		{name: "other audience", token: "valid", audiences: []string{"vault"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			done, err := conditions.New(client.Resources()).TokenReviewSucceeds(test.token, test.audiences)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, done)
+			}
+		})
+	}
+}
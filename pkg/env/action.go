@@ -36,6 +36,8 @@ const (
 type action struct {
 	role  actionRole
 	funcs []types.EnvFunc
+	// rollback undoes the funcs of a setup action (see SetupWithRollback)
+	rollback types.EnvFunc
 }
 
 func (a action) run(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
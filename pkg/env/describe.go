@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// ActionSummary describes the funcs registered, in an
+// environment, for an action role (i.e. Setup).
+type ActionSummary struct {
+	Role  string
+	Funcs int
+}
+
+func (s ActionSummary) String() string {
+	return fmt.Sprintf("%s: %d func(s)", s.Role, s.Funcs)
+}
+
+// Describe returns a human-readable summary of the actions registered in the
+// environment and of the steps of the features that would be tested, in
+// order, including why a feature would be skipped. Nothing is executed.
+func (e *testEnv) Describe(testFeatures ...types.Feature) string {
+	var sb strings.Builder
+
+	sb.WriteString("Actions:\n")
+	for _, summary := range e.actionSummaries() {
+		fmt.Fprintf(&sb, "  %s\n", summary)
+	}
+
+	sb.WriteString("Features:\n")
+	for _, feature := range sortFeatures(testFeatures) {
+		feature = e.withGlobalLabels(feature)
+		fmt.Fprintf(&sb, "  %s", feature.Name())
+		if labels := formatLabels(feature.Labels()); labels != "" {
+			fmt.Fprintf(&sb, " [%s]", labels)
+		}
+		if reason := e.skipReason(feature); reason != "" {
+			fmt.Fprintf(&sb, " (skipped: %s)\n", reason)
+			continue
+		}
+		sb.WriteString("\n")
+		for _, step := range feature.Steps() {
+			fmt.Fprintf(&sb, "    %s: %s\n", levelName(step.Level()), step.Name())
+		}
+	}
+
+	return sb.String()
+}
+
+func (e *testEnv) actionSummaries() []ActionSummary {
+	return []ActionSummary{
+		{Role: "Setup", Funcs: e.countFuncs(roleSetup)},
+		{Role: "BeforeEachTest", Funcs: e.countFuncs(roleBeforeTest) + len(e.beforeTestCleanupFuncs)},
+		{Role: "BeforeEachFeature", Funcs: e.countFuncs(roleBeforeFeature) + len(e.beforeFeatureFuncs)},
+		{Role: "AfterEachAssessment", Funcs: len(e.afterAssessmentFuncs)},
+		{Role: "AfterEachFeature", Funcs: e.countFuncs(roleAfterFeature) + len(e.afterFeatureFuncs)},
+		{Role: "AfterEachTest", Funcs: e.countFuncs(roleAfterTest)},
+		{Role: "Finish", Funcs: e.countFuncs(roleFinish)},
+	}
+}
+
+func (e *testEnv) countFuncs(r actionRole) int {
+	count := 0
+	for _, a := range e.getActionsByRole(r) {
+		count += len(a.funcs)
+	}
+	return count
+}
+
+// skipReason returns why the feature would be skipped
+// by the environment's filters, or an empty string.
+func (e *testEnv) skipReason(f types.Feature) string {
+	if e.cfg.FeatureRegex() != nil && !e.cfg.FeatureRegex().MatchString(f.Name()) {
+		return "name not matched"
+	}
+	// the labels are checked in key order to report the same one on every run
+	labels := e.cfg.Labels()
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if f.Labels()[key] != labels[key] {
+			return fmt.Sprintf("label %s=%s not matched", key, labels[key])
+		}
+	}
+	return ""
+}
+
+func formatLabels(labels types.Labels) string {
+	var pairs []string
+	for key, val := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, val))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func levelName(level types.Level) string {
+	switch level {
+	case types.LevelSetup:
+		return "setup"
+	case types.LevelAssess:
+		return "assess"
+	case types.LevelTeardown:
+		return "teardown"
+	default:
+		return "unknown"
+	}
+}
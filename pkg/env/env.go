@@ -20,10 +20,22 @@ package env
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 	"sigs.k8s.io/e2e-framework/pkg/internal/types"
@@ -40,6 +52,26 @@ type testEnv struct {
 	ctx     context.Context
 	cfg     *envconf.Config
 	actions []action
+	labels  types.Labels
+	cancel  context.CancelFunc
+
+	beforeTestCleanupFuncs []types.BeforeTestWithCleanupFunc
+	beforeFeatureFuncs     []types.FeatureEnvFunc
+	afterFeatureFuncs      []types.FeatureEnvFunc
+	afterAssessmentFuncs   []types.AfterAssessmentFunc
+	middlewares            []func(types.EnvFunc) types.EnvFunc
+
+	recorder       record.EventRecorder
+	eventSink      *eventSink
+	eventNamespace string
+
+	suiteTimeout time.Duration
+	// runningFeatures counts the features being tested, by name
+	runningFeatures   map[string]int
+	runningFeaturesMu sync.Mutex
+	// failedFeature is the name of the first failed feature
+	failedFeature   string
+	failedFeatureMu sync.Mutex
 }
 
 // New creates a test environment with no config attached.
@@ -79,13 +111,94 @@ func (e *testEnv) WithContext(ctx context.Context) types.Environment {
 		panic("nil context") // this should never happen
 	}
 	env := &testEnv{
-		ctx: ctx,
-		cfg: e.cfg,
+		ctx:            ctx,
+		cfg:            e.cfg,
+		labels:         e.labels,
+		recorder:       e.recorder,
+		eventSink:      e.eventSink,
+		eventNamespace: e.eventNamespace,
+		suiteTimeout:   e.suiteTimeout,
 	}
 	env.actions = append(env.actions, e.actions...)
+	env.beforeTestCleanupFuncs = append(env.beforeTestCleanupFuncs, e.beforeTestCleanupFuncs...)
+	env.beforeFeatureFuncs = append(env.beforeFeatureFuncs, e.beforeFeatureFuncs...)
+	env.afterFeatureFuncs = append(env.afterFeatureFuncs, e.afterFeatureFuncs...)
+	env.afterAssessmentFuncs = append(env.afterAssessmentFuncs, e.afterAssessmentFuncs...)
+	env.middlewares = append(env.middlewares, e.middlewares...)
+	return env
+}
+
+// Clone returns a copy of the environment, with a deep copy of its
+// configuration, to which actions can be added without affecting
+// the original environment. Both environments share the context.
+func (e *testEnv) Clone() types.Environment {
+	env := &testEnv{
+		ctx:            e.ctx,
+		cfg:            e.cfg.DeepCopy(),
+		recorder:       e.recorder,
+		eventSink:      e.eventSink,
+		eventNamespace: e.eventNamespace,
+		suiteTimeout:   e.suiteTimeout,
+	}
+	if e.labels != nil {
+		env.labels = make(types.Labels, len(e.labels))
+		for key, val := range e.labels {
+			env.labels[key] = val
+		}
+	}
+	for _, a := range e.actions {
+		env.actions = append(env.actions, action{role: a.role, funcs: append([]types.EnvFunc(nil), a.funcs...), rollback: a.rollback})
+	}
+	env.beforeTestCleanupFuncs = append(env.beforeTestCleanupFuncs, e.beforeTestCleanupFuncs...)
+	env.beforeFeatureFuncs = append(env.beforeFeatureFuncs, e.beforeFeatureFuncs...)
+	env.afterFeatureFuncs = append(env.afterFeatureFuncs, e.afterFeatureFuncs...)
+	env.afterAssessmentFuncs = append(env.afterAssessmentFuncs, e.afterAssessmentFuncs...)
+	env.middlewares = append(env.middlewares, e.middlewares...)
 	return env
 }
 
+// WithContextTimeout wraps the environment's context with a timeout of
+// duration d. This provides a suite-wide deadline that propagates to all
+// environment funcs and feature steps. Unlike the go test -timeout flag,
+// which terminates the test process, an expired deadline lets tests fail
+// cleanly. The Finish operations run with a fresh context, holding the
+// values of the environment's context, when the deadline has expired. The
+// context is cancelled after the Finish operations are executed.
+func (e *testEnv) WithContextTimeout(d time.Duration) types.Environment {
+	if e.ctx == nil {
+		panic("context not set") // something is terribly wrong.
+	}
+	e.ctx, e.cancel = context.WithTimeout(e.ctx, d)
+	return e
+}
+
+// WithSuiteTimeout sets a deadline for the whole test suite, from the
+// moment Run is called. When it expires, Run logs the features being tested
+// as timed out, executes the Finish operations and returns a non-zero exit
+// code (see Run). It takes precedence over the timeout of the environment
+// config (see envconf.Config.WithTimeout).
+func (e *testEnv) WithSuiteTimeout(d time.Duration) types.Environment {
+	e.suiteTimeout = d
+	return e
+}
+
+// timeout returns the deadline of the test suite, a value
+// of 0 or less means no deadline
+func (e *testEnv) timeout() time.Duration {
+	if e.suiteTimeout > 0 {
+		return e.suiteTimeout
+	}
+	return e.cfg.Timeout()
+}
+
+// WithGlobalLabels sets labels that are applied to all features tested
+// by this environment. At execution time, global labels are merged with
+// each feature's labels, with the feature's labels taking precedence.
+func (e *testEnv) WithGlobalLabels(labels map[string]string) types.Environment {
+	e.labels = labels
+	return e
+}
+
 // Setup registers environment operations that are executed once
 // prior to the environment being ready and prior to any test.
 func (e *testEnv) Setup(funcs ...Func) types.Environment {
@@ -96,6 +209,18 @@ func (e *testEnv) Setup(funcs ...Func) types.Environment {
 	return e
 }
 
+// SetupWithRollback registers a setup operation, like Setup, paired with
+// its rollback. When a later setup operation fails, the rollbacks of the
+// setup operations that completed are executed in reverse order, like
+// deferred calls, before the test suite exits.
+func (e *testEnv) SetupWithRollback(fn Func, rollback Func) types.Environment {
+	if fn == nil {
+		return e
+	}
+	e.actions = append(e.actions, action{role: roleSetup, funcs: []types.EnvFunc{fn}, rollback: rollback})
+	return e
+}
+
 // BeforeEachTest registers environment funcs that are executed
 // before each Env.Test(...)
 func (e *testEnv) BeforeEachTest(funcs ...Func) types.Environment {
@@ -106,6 +231,15 @@ func (e *testEnv) BeforeEachTest(funcs ...Func) types.Environment {
 	return e
 }
 
+// BeforeEachTestWithCleanup registers funcs that are executed before each
+// Env.Test(...), after the funcs registered with BeforeEachTest. Unlike
+// BeforeEachTest funcs, they receive the test's *testing.T and can return a
+// cleanup func which is registered using t.Cleanup.
+func (e *testEnv) BeforeEachTestWithCleanup(funcs ...types.BeforeTestWithCleanupFunc) types.Environment {
+	e.beforeTestCleanupFuncs = append(e.beforeTestCleanupFuncs, funcs...)
+	return e
+}
+
 // BeforeEachFeature registers step functions that are executed
 // before each Feature is tested during env.Test call.
 func (e *testEnv) BeforeEachFeature(funcs ...Func) types.Environment {
@@ -126,6 +260,22 @@ func (e *testEnv) AfterEachFeature(funcs ...Func) types.Environment {
 	return e
 }
 
+// BeforeEachFeatureWithT registers funcs that are executed before each
+// feature is tested. Unlike BeforeEachFeature funcs, they are executed
+// within the feature's subtest and receive its *testing.T, which can be
+// used to log feature-specific information or to fail the feature.
+func (e *testEnv) BeforeEachFeatureWithT(funcs ...types.FeatureEnvFunc) types.Environment {
+	e.beforeFeatureFuncs = append(e.beforeFeatureFuncs, funcs...)
+	return e
+}
+
+// AfterEachFeatureWithT registers funcs that are executed after each
+// feature is tested, within the feature's subtest (see BeforeEachFeatureWithT).
+func (e *testEnv) AfterEachFeatureWithT(funcs ...types.FeatureEnvFunc) types.Environment {
+	e.afterFeatureFuncs = append(e.afterFeatureFuncs, funcs...)
+	return e
+}
+
 // AfterEachTest registers environment funcs that are executed
 // after each Env.Test(...).
 func (e *testEnv) AfterEachTest(funcs ...Func) types.Environment {
@@ -136,6 +286,16 @@ func (e *testEnv) AfterEachTest(funcs ...Func) types.Environment {
 	return e
 }
 
+// AfterEachAssessment registers funcs that are executed after each
+// assessment completes during an env.Test call. The funcs are invoked
+// from the assessment's t.Cleanup, so the reported pass/fail status
+// reflects the final result of the assessment. Skipped assessments
+// are not reported.
+func (e *testEnv) AfterEachAssessment(funcs ...types.AfterAssessmentFunc) types.Environment {
+	e.afterAssessmentFuncs = append(e.afterAssessmentFuncs, funcs...)
+	return e
+}
+
 // Test executes a feature test from within a TestXXX function.
 //
 // Feature setups and teardowns are executed at the same *testing.T
@@ -146,56 +306,272 @@ func (e *testEnv) AfterEachTest(funcs ...Func) types.Environment {
 // Feature tests will have access to and able to update the context
 // passed to it.
 //
+// Features are tested in ascending order of their order value
+// (see features.FeatureBuilder.WithOrder). Features with the same order
+// value sharing declared context keys are logged as potential data races.
+//
 // BeforeTest and AfterTest operations are executed before and after
 // the feature is tested respectively.
+//
+// When the environment config has FailFast enabled, the first feature failure
+// is recorded by the environment: the remaining features are skipped, t.FailNow
+// is called once the AfterTest operations are executed, and the tests that
+// call Test or TestInParallel afterwards are skipped. Note that t.FailNow only
+// stops the calling test: tests running in parallel (see t.Parallel) are not
+// interrupted.
 func (e *testEnv) Test(t *testing.T, testFeatures ...types.Feature) {
 	if e.ctx == nil {
 		panic("context not set") // something is terribly wrong.
 	}
 
+	if reason := e.failFastReason(); reason != "" {
+		t.Skip(reason)
+	}
+
 	if len(testFeatures) == 0 {
 		t.Log("No test testFeatures provided, skipping test")
 		return
 	}
 
-	// execute the beforeTest functions
-	beforeTestActions := e.getBeforeTestActions()
-	var err error
-	for _, action := range beforeTestActions {
-		if e.ctx, err = action.run(e.ctx, e.cfg); err != nil {
-			t.Fatalf("BeforeEachTest failure: %s", err)
-		}
+	testFeatures = sortFeatures(testFeatures)
+	for _, conflict := range orderConflicts(testFeatures) {
+		t.Logf("potential data race: %s", conflict)
 	}
 
+	// execute the beforeTest functions
+	e.runBeforeTest(t)
+
 	// execute each feature
 	beforeFeatureActions := e.getBeforeFeatureActions()
 	afterFeatureActions := e.getAfterFeatureActions()
 	for _, feature := range testFeatures {
+		feature = e.withGlobalLabels(feature)
+
 		// execute beforeFeature actions
-		for _, action := range beforeFeatureActions {
-			if e.ctx, err = action.run(e.ctx, e.cfg); err != nil {
-				t.Fatalf("BeforeEachTest failure: %s", err)
-			}
-		}
+		e.runActions(t, beforeFeatureActions, "BeforeEachFeature")
 
 		// execute feature test
 		e.ctx = e.execFeature(e.ctx, t, feature)
 
-		// execute beforeFeature actions
-		for _, action := range afterFeatureActions {
+		// execute afterFeature actions
+		e.runActions(t, afterFeatureActions, "AfterEachFeature")
+
+		if t.Failed() {
+			e.recordFailure(feature.Name())
+		}
+		if reason := e.failFastReason(); reason != "" {
+			t.Logf("%s: skipping remaining features", reason)
+			break
+		}
+	}
+
+	// execute afterTest functions
+	e.runAfterTest(t)
+
+	if t.Failed() && e.failFastReason() != "" {
+		t.FailNow()
+	}
+}
+
+// TestInParallel executes feature tests, like Test, but tests the features
+// concurrently, each in its own goroutine. The number of features tested at
+// the same time is limited by the environment config parallelism (see
+// envconf.Config.WithParallelism); all features are tested concurrently when
+// it is not set.
+//
+// Each feature starts with the environment's context, as updated by the
+// BeforeEachFeature operations, so context values written by a feature
+// are not visible to the other features. BeforeEachFeature and
+// AfterEachFeature operations are executed in each feature's goroutine
+// while holding a lock, as they update the environment's context.
+// Their failures are reported once all features have been tested.
+//
+// When the environment config has FailFast enabled, the features that have
+// not started yet are skipped after a feature fails, as in Test. The features
+// being tested are not interrupted.
+func (e *testEnv) TestInParallel(t *testing.T, testFeatures ...types.Feature) {
+	if e.ctx == nil {
+		panic("context not set") // something is terribly wrong.
+	}
+
+	if reason := e.failFastReason(); reason != "" {
+		t.Skip(reason)
+	}
+
+	if len(testFeatures) == 0 {
+		t.Log("No test testFeatures provided, skipping test")
+		return
+	}
+
+	testFeatures = sortFeatures(testFeatures)
+	for _, conflict := range orderConflicts(testFeatures) {
+		t.Logf("potential data race: %s", conflict)
+	}
+
+	// execute the beforeTest functions
+	e.runBeforeTest(t)
+
+	parallelism := e.cfg.Parallelism()
+	if parallelism <= 0 {
+		parallelism = len(testFeatures)
+	}
+
+	beforeFeatureActions := e.getBeforeFeatureActions()
+	afterFeatureActions := e.getAfterFeatureActions()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, parallelism)
+	)
+
+	// runFeatureActions executes the actions, updating the environment's
+	// context, and returns the updated context or nil on failure
+	runFeatureActions := func(actions []action, role string, featName string) context.Context {
+		mu.Lock()
+		defer mu.Unlock()
+		var err error
+		for _, action := range actions {
 			if e.ctx, err = action.run(e.ctx, e.cfg); err != nil {
-				t.Fatalf("BeforeEachTest failure: %s", err)
+				errs = append(errs, fmt.Errorf("%s failure: feature %s: %w", role, featName, err))
+				return nil
 			}
 		}
+		return e.ctx
+	}
+
+	for _, feature := range testFeatures {
+		feature := e.withGlobalLabels(feature)
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if reason := e.failFastReason(); reason != "" {
+				t.Logf("%s: skipping feature %s", reason, feature.Name())
+				return
+			}
+
+			// execute beforeFeature actions
+			ctx := runFeatureActions(beforeFeatureActions, "BeforeEachFeature", feature.Name())
+			if ctx == nil {
+				e.recordFailure(feature.Name())
+				return
+			}
+
+			// execute feature test
+			e.execFeature(ctx, t, feature)
+
+			// execute afterFeature actions
+			if runFeatureActions(afterFeatureActions, "AfterEachFeature", feature.Name()) == nil {
+				e.recordFailure(feature.Name())
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		t.Error(err)
 	}
 
 	// execute afterTest functions
-	afterTestActions := e.getAfterTestActions()
-	for _, action := range afterTestActions {
-		if e.ctx, err = action.run(e.ctx, e.cfg); err != nil {
-			t.Fatalf("AfterEachTest failure: %s", err)
+	e.runAfterTest(t)
+
+	if t.Failed() && e.failFastReason() != "" {
+		t.FailNow()
+	}
+}
+
+// TestWithDeadline executes feature tests, like Test, using a context
+// derived from both the environment's context and ctx: the derived context
+// is cancelled when either of them is done, letting callers cancel this
+// run without affecting the environment.
+//
+// The derived context is only used for the duration of the call; once it
+// returns, the environment's context is restored, which discards context
+// updates made by the features and the BeforeTest/AfterTest operations.
+func (e *testEnv) TestWithDeadline(ctx context.Context, t *testing.T, testFeatures ...types.Feature) {
+	if ctx == nil {
+		panic("nil context") // this should never happen
+	}
+
+	envCtx := e.ctx
+	derivedCtx, cancel := mergeContexts(envCtx, ctx)
+	defer cancel()
+
+	e.ctx = derivedCtx
+	defer func() { e.ctx = envCtx }()
+
+	e.Test(t, testFeatures...)
+}
+
+// mergeContexts returns a context, with the values of parent, that is
+// cancelled when either parent or other is done. The returned context
+// also inherits the deadline of other, if any.
+func mergeContexts(parent, other context.Context) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if deadline, ok := other.Deadline(); ok {
+		ctx, cancel = context.WithDeadline(parent, deadline)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+
+	go func() {
+		select {
+		case <-other.Done():
+			cancel()
+		case <-ctx.Done():
 		}
+	}()
+
+	return ctx, cancel
+}
+
+// Benchmark executes the features in benchmark mode. Each feature runs
+// as a sub-benchmark, like a subtest in Test, with its setups and teardowns
+// executed once. Each assessment runs as a sub-benchmark of its feature
+// that executes the assessment b.N times.
+//
+// The duration of the assessment, per iteration, is reported as the s/assess
+// metric of its sub-benchmark.
+//
+// Benchmarks have no *testing.T to pass to step functions: the steps of the
+// features benchmarked must be registered with a testing.TB function (see
+// features.FeatureBuilder.AssessWithTB), which is called with the *testing.B
+// of the sub-benchmark. Features with other steps are skipped. Only the
+// environment funcs are executed, the funcs requiring a *testing.T (i.e.
+// BeforeEachFeatureWithT) are not.
+func (e *testEnv) Benchmark(b *testing.B, testFeatures ...types.Feature) {
+	if e.ctx == nil {
+		panic("context not set") // something is terribly wrong.
+	}
+
+	if len(testFeatures) == 0 {
+		b.Log("No test testFeatures provided, skipping benchmark")
+		return
+	}
+
+	testFeatures = sortFeatures(testFeatures)
+
+	// BeforeEachTestWithCleanup funcs require a *testing.T, only the
+	// environment funcs are executed here.
+	e.runActions(b, e.getBeforeTestActions(), "BeforeEachTest")
+
+	beforeFeatureActions := e.getBeforeFeatureActions()
+	afterFeatureActions := e.getAfterFeatureActions()
+	for _, feature := range testFeatures {
+		feature = e.withGlobalLabels(feature)
+
+		e.runActions(b, beforeFeatureActions, "BeforeEachFeature")
+		e.ctx = e.execFeature(e.ctx, b, feature)
+		e.runActions(b, afterFeatureActions, "AfterEachFeature")
 	}
+
+	e.runAfterTest(b)
 }
 
 // Finish registers funcs that are executed at the end of the
@@ -209,40 +585,313 @@ func (e *testEnv) Finish(funcs ...Func) types.Environment {
 	return e
 }
 
+// SetupIf registers setup funcs, like Setup, that are only
+// executed when condition returns true (see When).
+func (e *testEnv) SetupIf(condition func(context.Context, *envconf.Config) bool, funcs ...Func) types.Environment {
+	return e.Setup(whenAll(condition, funcs)...)
+}
+
+// BeforeEachTestIf registers funcs, like BeforeEachTest, that are
+// only executed when condition returns true (see When).
+func (e *testEnv) BeforeEachTestIf(condition func(context.Context, *envconf.Config) bool, funcs ...Func) types.Environment {
+	return e.BeforeEachTest(whenAll(condition, funcs)...)
+}
+
+// AfterEachTestIf registers funcs, like AfterEachTest, that are
+// only executed when condition returns true (see When).
+func (e *testEnv) AfterEachTestIf(condition func(context.Context, *envconf.Config) bool, funcs ...Func) types.Environment {
+	return e.AfterEachTest(whenAll(condition, funcs)...)
+}
+
+// FinishIf registers finish funcs, like Finish, that are only
+// executed when condition returns true (see When).
+func (e *testEnv) FinishIf(condition func(context.Context, *envconf.Config) bool, funcs ...Func) types.Environment {
+	return e.Finish(whenAll(condition, funcs)...)
+}
+
+// whenAll wraps each of the funcs with When
+func whenAll(condition func(context.Context, *envconf.Config) bool, funcs []Func) []Func {
+	wrapped := make([]Func, len(funcs))
+	for i, fn := range funcs {
+		wrapped[i] = When(condition, fn)
+	}
+	return wrapped
+}
+
 // Run is to launch the test suite from a TestMain function.
 // It will run m.Run() and exercise all test functions in the
 // package.  This method will all Env.Setup operations prior to
 // starting the tests and run all Env.Finish operations after
 // before completing the suite.
 //
+// When the environment has a suite timeout (see WithSuiteTimeout and
+// envconf.Config.WithTimeout), the setup operations and the tests run with a
+// context that expires after the timeout. If it expires before the tests complete, the Finish operations are
+// executed with a fresh context, limited to 30 seconds, and a non-zero exit
+// code is returned.
+//
+// When the process receives SIGINT or SIGTERM, the environment's context is
+// cancelled and the tests are given 30 seconds to stop. The Finish operations
+// are then executed the same way and exit code 130 is returned.
+//
 func (e *testEnv) Run(m *testing.M) int {
+	return e.run(m.Run)
+}
+
+const (
+	// finishTimeout is the time given to the Finish operations
+	// once the suite timeout has expired or the suite is interrupted
+	finishTimeout = 30 * time.Second
+	// interruptTimeout is the time given to the tests to stop
+	// once the suite is interrupted
+	interruptTimeout = 30 * time.Second
+	// interruptExitCode is returned when the suite is interrupted
+	interruptExitCode = 130
+)
+
+// notifyContext returns a copy of the parent context that is done when one
+// of the signals arrives. Replaced in tests, where signals are process-wide.
+var notifyContext = signal.NotifyContext
+
+// run executes the setup operations, the tests with runTests
+// and the finish operations, and returns the tests exit code.
+func (e *testEnv) run(runTests func() int) int {
 	if e.ctx == nil {
 		panic("context not set") // something is terribly wrong.
 	}
 
-	setups := e.getSetupActions()
+	if timeout := e.timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		e.ctx, cancel = context.WithTimeout(e.ctx, timeout)
+		defer cancel()
+	}
+
+	// upon SIGINT or SIGTERM, the environment's context is cancelled
+	// so the tests can stop, and the finish actions still run
+	interrupt, stopInterrupt := notifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopInterrupt()
+	var cancel context.CancelFunc
+	e.ctx, cancel = context.WithCancel(e.ctx)
+	defer cancel()
+	go func() {
+		<-interrupt.Done()
+		cancel()
+	}()
+
 	// fail fast on setup, upon err exit
-	var err error
+	if err := e.runSetupActions(); err != nil {
+		e.shutdownEventSink()
+		log.Fatal(err)
+	}
+
+	setupCtx := e.ctx
+	var exitCode int
+	var timedOut, interrupted bool
+	if reason, skip := e.ctx.Value(skipSuiteKey{}).(string); skip {
+		log.Printf("Skipping test suite: %s", reason)
+	} else {
+		exitCode, timedOut, interrupted = e.runTests(runTests, interrupt) // exec test suite
+	}
+	if exitCode != 0 {
+		exportLogs(setupCtx)
+	}
+	if timedOut || interrupted {
+		if timedOut {
+			log.Printf("Test suite did not complete within %s, running finish actions", e.timeout())
+			for _, name := range e.getRunningFeatures() {
+				log.Printf("Feature %q timed out", name)
+			}
+		} else {
+			log.Print("Test suite interrupted, running finish actions")
+		}
+		// the tests may still be updating the environment's context: the finish
+		// actions use a fresh context, with the values set by the setup actions
+		finishCtx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+		defer cancel()
+		e.runFinishActions(&valuesContext{Context: finishCtx, values: setupCtx})
+	} else if e.ctx.Err() != nil {
+		// the environment's context expired (see WithContextTimeout): the finish
+		// actions use a fresh context, with the values set by the tests
+		finishCtx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+		defer cancel()
+		e.ctx = e.runFinishActions(&valuesContext{Context: finishCtx, values: e.ctx})
+	} else {
+		e.ctx = e.runFinishActions(e.ctx)
+	}
+	e.shutdownEventSink()
+
+	if e.cancel != nil {
+		e.cancel()
+	}
+
+	return exitCode
+}
+
+// runSetupActions executes the setup actions, passing the environment's
+// context down to each of them, until one fails or the suite is skipped.
+// Upon error, the rollbacks of the completed setup actions are executed.
+func (e *testEnv) runSetupActions() error {
+	setups := e.getSetupActions()
+	if len(setups) > 0 {
+		e.recordEvent(corev1.EventTypeNormal, EventReasonSetupStarted, "Running %d setup actions", len(setups))
+	}
+
+	var rollbacks []types.EnvFunc
 	for _, setup := range setups {
-		// context passed down to each setup
-		if e.ctx, err = setup.run(e.ctx, e.cfg); err != nil {
-			log.Fatal(err)
+		ctx, err := setup.run(e.ctx, e.cfg)
+		if err != nil {
+			e.recordEvent(corev1.EventTypeWarning, EventReasonSetupFailed, "Setup failed: %s", err)
+			e.rollbackSetups(rollbacks)
+			return err
+		}
+		e.ctx = ctx
+		if setup.rollback != nil {
+			rollbacks = append(rollbacks, setup.rollback)
+		}
+		if e.ctx.Value(skipSuiteKey{}) != nil {
+			break
 		}
 	}
+	return nil
+}
 
-	exitCode := m.Run() // exec test suite
+// rollbackSetups executes the rollbacks of the completed setup operations,
+// in reverse order. Upon error, it logs and continues.
+func (e *testEnv) rollbackSetups(rollbacks []types.EnvFunc) {
+	ctx := e.ctx
+	for i := len(rollbacks) - 1; i >= 0; i-- {
+		var err error
+		if ctx, err = rollbacks[i](ctx, e.cfg); err != nil {
+			log.Printf("Setup rollback failed: %s", err)
+		}
+	}
+}
 
-	finishes := e.getFinishActions()
-	// attempt to gracefully clean up.
-	// Upon error, log and continue.
-	for _, fin := range finishes {
-		// context passed down to each finish step
-		if e.ctx, err = fin.run(e.ctx, e.cfg); err != nil {
+// runFinishActions executes the finish actions, passing ctx down to each
+// of them, and returns the resulting context. Upon error, it logs and continues.
+func (e *testEnv) runFinishActions(ctx context.Context) context.Context {
+	var err error
+	for _, fin := range e.getFinishActions() {
+		if ctx, err = fin.run(ctx, e.cfg); err != nil {
 			log.Println(err)
 		}
 	}
+	return ctx
+}
 
-	return exitCode
+// runTests runs the tests and returns their exit code. When the environment
+// has a suite timeout, it returns early, reporting that the tests timed out
+// with a non-zero exit code, if the environment's context expires first.
+// When interrupt is done first, it waits for the tests to stop, at most
+// interruptTimeout, and reports that the tests were interrupted.
+func (e *testEnv) runTests(runTests func() int, interrupt context.Context) (exitCode int, timedOut, interrupted bool) {
+	if interrupt.Err() != nil {
+		return interruptExitCode, false, true
+	}
+
+	done := make(chan int, 1)
+	go func() { done <- runTests() }()
+
+	// without suite timeout, the expiration of the context is ignored
+	var expired <-chan struct{}
+	if e.timeout() > 0 {
+		expired = e.ctx.Done()
+	}
+
+	select {
+	case exitCode = <-done:
+		return exitCode, false, false
+	case <-expired:
+		if interrupt.Err() == nil {
+			return 1, true, false
+		}
+	case <-interrupt.Done():
+	}
+
+	log.Printf("Test suite interrupted, waiting up to %s for the tests to stop", interruptTimeout)
+	select {
+	case <-done:
+	case <-time.After(interruptTimeout):
+	}
+	return interruptExitCode, false, true
+}
+
+// RunSubset launches the test suite from a TestMain function, like Run,
+// but only runs the tests with names for which predicate returns true.
+//
+// Since testing.M does not expose its tests, the test names are retrieved
+// using reflection and the selection is applied by setting flag -test.run
+// with a regular expression that only matches the selected names. This
+// overrides any -run pattern passed to go test, predicate can read it with
+// flag.Lookup("test.run") to honor it.
+func (e *testEnv) RunSubset(m *testing.M, predicate func(testName string) bool) int {
+	names, err := testNames(m)
+	if err != nil {
+		log.Fatalf("run subset: %s", err)
+	}
+
+	regex, err := subsetRunRegex(names, predicate)
+	if err != nil {
+		log.Fatalf("run subset: %s", err)
+	}
+
+	if err := flag.Set("test.run", regex); err != nil {
+		log.Fatalf("run subset: %s", err)
+	}
+
+	return e.Run(m)
+}
+
+// testNames returns the names of the top-level tests registered in m
+func testNames(m *testing.M) ([]string, error) {
+	tests := reflect.ValueOf(m).Elem().FieldByName("tests")
+	if !tests.IsValid() || tests.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("tests of %T not found", m)
+	}
+
+	names := make([]string, 0, tests.Len())
+	for i := 0; i < tests.Len(); i++ {
+		name := tests.Index(i).FieldByName("Name")
+		if !name.IsValid() || name.Kind() != reflect.String {
+			return nil, fmt.Errorf("name of test %d of %T not found", i, m)
+		}
+		names = append(names, name.String())
+	}
+	return names, nil
+}
+
+// subsetRunRegex returns a regular expression that exclusively
+// matches the names for which predicate returns true.
+func subsetRunRegex(names []string, predicate func(string) bool) (string, error) {
+	var selected []string
+	for _, name := range names {
+		if predicate(name) {
+			selected = append(selected, regexp.QuoteMeta(name))
+		}
+	}
+
+	// test names are never empty, so ^$ matches no test
+	regex := "^$"
+	if len(selected) > 0 {
+		regex = fmt.Sprintf("^(%s)$", strings.Join(selected, "|"))
+	}
+
+	if _, err := regexp.Compile(regex); err != nil {
+		return "", fmt.Errorf("invalid test selection regex %q: %w", regex, err)
+	}
+	return regex, nil
+}
+
+// Middleware registers a func that wraps each environment func of the
+// actions (i.e. Setup, BeforeEachTest) when they are executed, including
+// funcs registered after the middleware. Middlewares compose in
+// registration order: the first one registered is the outermost.
+func (e *testEnv) Middleware(fn func(types.EnvFunc) types.EnvFunc) types.Environment {
+	if fn == nil {
+		return e
+	}
+	e.middlewares = append(e.middlewares, fn)
+	return e
 }
 
 func (e *testEnv) getActionsByRole(r actionRole) []action {
@@ -253,13 +902,32 @@ func (e *testEnv) getActionsByRole(r actionRole) []action {
 	var result []action
 	for _, a := range e.actions {
 		if a.role == r {
-			result = append(result, a)
+			result = append(result, e.withMiddlewares(a))
 		}
 	}
 
 	return result
 }
 
+// withMiddlewares returns a copy of the action with its funcs wrapped by the middlewares
+func (e *testEnv) withMiddlewares(a action) action {
+	if len(e.middlewares) == 0 {
+		return a
+	}
+
+	wrapped := action{role: a.role, funcs: make([]types.EnvFunc, len(a.funcs)), rollback: a.rollback}
+	for i, f := range a.funcs {
+		if f == nil {
+			continue
+		}
+		for j := len(e.middlewares) - 1; j >= 0; j-- {
+			f = e.middlewares[j](f)
+		}
+		wrapped.funcs[i] = f
+	}
+	return wrapped
+}
+
 func (e *testEnv) getSetupActions() []action {
 	return e.getActionsByRole(roleSetup)
 }
@@ -284,39 +952,371 @@ func (e *testEnv) getFinishActions() []action {
 	return e.getActionsByRole(roleFinish)
 }
 
-func (e *testEnv) execFeature(ctx context.Context, t *testing.T, f types.Feature) context.Context {
+// runBeforeTest executes the BeforeEachTest and BeforeEachTestWithCleanup funcs
+func (e *testEnv) runBeforeTest(t *testing.T) {
+	e.runActions(t, e.getBeforeTestActions(), "BeforeEachTest")
+
+	var err error
+	for _, fn := range e.beforeTestCleanupFuncs {
+		if fn == nil {
+			continue
+		}
+		var cleanup func()
+		if e.ctx, cleanup, err = fn(e.ctx, e.cfg, t); err != nil {
+			t.Fatalf("BeforeEachTestWithCleanup failure: %s", err)
+		}
+		if cleanup != nil {
+			t.Cleanup(cleanup)
+		}
+	}
+}
+
+// runAfterTest executes the AfterEachTest funcs
+func (e *testEnv) runAfterTest(tb testing.TB) {
+	e.runActions(tb, e.getAfterTestActions(), "AfterEachTest")
+}
+
+// runActions runs the actions in order, updating the environment context,
+// and fails tb on the first error.
+func (e *testEnv) runActions(tb testing.TB, actions []action, role string) {
+	var err error
+	for _, action := range actions {
+		if e.ctx, err = action.run(e.ctx, e.cfg); err != nil {
+			tb.Fatalf("%s failure: %s", role, err)
+		}
+	}
+}
+
+// sortFeatures returns a copy of the features sorted by order value, keeping
+// the registration order of features with the same order value
+func sortFeatures(testFeatures []types.Feature) []types.Feature {
+	sorted := append([]types.Feature(nil), testFeatures...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order() < sorted[j].Order()
+	})
+	return sorted
+}
+
+// orderConflicts returns, for each pair of features with the same order value
+// that declare a common context key, a description of the conflict
+func orderConflicts(testFeatures []types.Feature) []string {
+	var conflicts []string
+	for i, first := range testFeatures {
+		for _, second := range testFeatures[i+1:] {
+			if first.Order() != second.Order() {
+				continue
+			}
+			for _, key := range sharedContextKeys(first.ContextKeys(), second.ContextKeys()) {
+				conflicts = append(conflicts, fmt.Sprintf("features %q and %q have the same order %d and share context key %v",
+					first.Name(), second.Name(), first.Order(), key))
+			}
+		}
+	}
+	return conflicts
+}
+
+// sharedContextKeys returns the comparable keys found in both first and second
+func sharedContextKeys(first, second []interface{}) []interface{} {
+	var shared []interface{}
+	for _, key := range first {
+		if key == nil || !reflect.TypeOf(key).Comparable() {
+			continue
+		}
+		for _, other := range second {
+			if other != nil && reflect.TypeOf(other).Comparable() && key == other {
+				shared = append(shared, key)
+				break
+			}
+		}
+	}
+	return shared
+}
+
+// withGlobalLabels returns f with the environment's global
+// labels merged into the feature's labels.
+func (e *testEnv) withGlobalLabels(f types.Feature) types.Feature {
+	if len(e.labels) == 0 {
+		return f
+	}
+
+	labels := make(types.Labels)
+	for k, v := range e.labels {
+		labels[k] = v
+	}
+	for k, v := range f.Labels() {
+		labels[k] = v
+	}
+
+	return &labeledFeature{Feature: f, labels: labels}
+}
+
+// resultContext returns the context resulting from testing feature f, which
+// started with envCtx and ended with featCtx. A feature tested with its own
+// base context (see features.FeatureBuilder.WithBaseContext) leaves the
+// environment's context unchanged.
+func resultContext(envCtx, featCtx context.Context, f types.Feature) context.Context {
+	if f.BaseContext() != nil {
+		return envCtx
+	}
+	return featCtx
+}
+
+// labeledFeature overrides the labels of a wrapped feature
+type labeledFeature struct {
+	types.Feature
+	labels types.Labels
+}
+
+func (f *labeledFeature) Labels() types.Labels {
+	return f.labels
+}
+
+// execFeature runs the feature as a subtest of tb, or as a sub-benchmark
+// when tb is a *testing.B.
+func (e *testEnv) execFeature(ctx context.Context, tb testing.TB, f types.Feature) context.Context {
 	featName := f.Name()
+	envCtx := ctx
+	if baseCtx := f.BaseContext(); baseCtx != nil {
+		ctx = baseCtx
+	}
 
 	// feature-level subtest
-	t.Run(featName, func(t *testing.T) {
-		if e.cfg.FeatureRegex() != nil && !e.cfg.FeatureRegex().MatchString(featName) {
-			t.Skipf(`Skipping feature "%s": name not matched`, featName)
+	subtest(tb, featName, func(tb testing.TB) {
+		if reason := e.skipReason(f); reason != "" {
+			tb.Skipf(`Skipping feature "%s": %s`, featName, reason)
+		}
+		if _, benchmark := tb.(*testing.B); benchmark {
+			for _, step := range f.Steps() {
+				if step.FuncTB() == nil {
+					tb.Skipf(`Skipping feature "%s": step "%s" requires a *testing.T, register it with a testing.TB function to benchmark it`, featName, step.Name())
+				}
+			}
+		}
+
+		e.recordEvent(corev1.EventTypeNormal, EventReasonFeatureStarted, "Feature %q started", featName)
+		e.trackRunningFeature(featName, 1)
+		defer e.trackRunningFeature(featName, -1)
+		if reporter := e.cfg.Reporter(); reporter != nil {
+			reporter.OnFeatureStart(featName)
+		}
+		tb.Cleanup(func() {
+			if tb.Failed() {
+				e.recordFailure(featName)
+				e.recordEvent(corev1.EventTypeWarning, EventReasonFeatureFailed, "Feature %q failed", featName)
+			}
+			if reporter := e.cfg.Reporter(); reporter != nil {
+				reporter.OnFeatureEnd(featName, !tb.Failed())
+			}
+		})
+
+		ctx = runFeatureFuncs(ctx, tb, e.cfg, e.beforeFeatureFuncs, "BeforeEachFeatureWithT")
+
+		// the feature steps run with a context that expires after the feature timeout
+		stepsCtx := ctx
+		var featureCtx context.Context
+		if timeout := f.Timeout(); timeout > 0 {
+			var cancel context.CancelFunc
+			featureCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+			ctx = featureCtx
+		}
+		timedOut := func() bool {
+			return featureCtx != nil && featureCtx.Err() == context.DeadlineExceeded && stepsCtx.Err() == nil
 		}
 
 		// setups run at feature-level
 		setups := features.GetStepsByLevel(f.Steps(), types.LevelSetup)
 		for _, setup := range setups {
-			ctx = setup.Func()(ctx, t, e.cfg)
+			ctx = e.callStep(ctx, tb, setup)
 		}
 
 		// assessments run as feature/assessment sub level
 		assessments := features.GetStepsByLevel(f.Steps(), types.LevelAssess)
 
-		for _, assess := range assessments {
-			t.Run(assess.Name(), func(t *testing.T) {
-				if e.cfg.AssessmentRegex() != nil && !e.cfg.AssessmentRegex().MatchString(assess.Name()) {
-					t.Skipf(`Skipping assessment "%s": name not matched`, assess.Name())
+		for i := 0; i < len(assessments) && !timedOut(); {
+			if !assessments[i].Parallel() {
+				ctx = e.execAssessment(ctx, tb, featName, assessments[i])
+				i++
+				continue
+			}
+
+			// adjacent parallel assessments run concurrently, using the same context,
+			// as parallel subtests of a feature/parallel group subtest, which returns
+			// once all of them completed so that the next sequential assessment
+			// waits for them. The contexts returned by parallel assessments are
+			// discarded.
+			var group []types.Step
+			for ; i < len(assessments) && assessments[i].Parallel(); i++ {
+				group = append(group, assessments[i])
+			}
+			groupCtx := ctx
+			subtest(tb, "parallel", func(tb testing.TB) {
+				for _, assess := range group {
+					e.execAssessment(groupCtx, tb, featName, assess)
 				}
-				ctx = assess.Func()(ctx, t, e.cfg)
 			})
 		}
 
+		// upon feature timeout, teardowns run with the context values
+		// set by the steps but without the expired deadline
+		if timedOut() {
+			tb.Errorf("feature timed out after %v", f.Timeout())
+			ctx = &valuesContext{Context: stepsCtx, values: ctx}
+		}
+
 		// teardowns run at feature-level
 		teardowns := features.GetStepsByLevel(f.Steps(), types.LevelTeardown)
 		for _, teardown := range teardowns {
-			ctx = teardown.Func()(ctx, t, e.cfg)
+			ctx = e.callStep(ctx, tb, teardown)
 		}
+
+		// the feature deadline does not apply beyond the feature steps
+		if featureCtx != nil {
+			ctx = &valuesContext{Context: stepsCtx, values: ctx}
+		}
+
+		ctx = runFeatureFuncs(ctx, tb, e.cfg, e.afterFeatureFuncs, "AfterEachFeatureWithT")
 	})
 
+	return resultContext(envCtx, ctx, f)
+}
+
+// trackRunningFeature adds delta to the count of running features named name
+func (e *testEnv) trackRunningFeature(name string, delta int) {
+	e.runningFeaturesMu.Lock()
+	defer e.runningFeaturesMu.Unlock()
+	if e.runningFeatures == nil {
+		e.runningFeatures = make(map[string]int)
+	}
+	e.runningFeatures[name] += delta
+	if e.runningFeatures[name] <= 0 {
+		delete(e.runningFeatures, name)
+	}
+}
+
+// recordFailure records featName as failed, unless a feature already failed
+func (e *testEnv) recordFailure(featName string) {
+	e.failedFeatureMu.Lock()
+	defer e.failedFeatureMu.Unlock()
+	if e.failedFeature == "" {
+		e.failedFeature = featName
+	}
+}
+
+// failFastReason returns why features are skipped, when the environment
+// config has FailFast enabled and a feature failed, or an empty string
+func (e *testEnv) failFastReason() string {
+	if !e.cfg.FailFast() {
+		return ""
+	}
+	e.failedFeatureMu.Lock()
+	defer e.failedFeatureMu.Unlock()
+	if e.failedFeature == "" {
+		return ""
+	}
+	return fmt.Sprintf("Fail fast: feature %q failed", e.failedFeature)
+}
+
+// getRunningFeatures returns the sorted names of the features being tested
+func (e *testEnv) getRunningFeatures() []string {
+	e.runningFeaturesMu.Lock()
+	defer e.runningFeaturesMu.Unlock()
+	names := make([]string, 0, len(e.runningFeatures))
+	for name := range e.runningFeatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// execAssessment runs the assessment as a feature/assessment subtest, which
+// calls t.Parallel for parallel assessments. When tb is a *testing.B, the
+// assessment runs b.N times as a sub-benchmark.
+func (e *testEnv) execAssessment(ctx context.Context, tb testing.TB, featName string, assess types.Step) context.Context {
+	subtest(tb, assess.Name(), func(tb testing.TB) {
+		if t, ok := tb.(*testing.T); ok && assess.Parallel() {
+			t.Parallel()
+		}
+		start := time.Now()
+		tb.Cleanup(func() {
+			if tb.Skipped() {
+				return
+			}
+			if tb.Failed() {
+				e.recordEvent(corev1.EventTypeWarning, EventReasonAssessmentFailed, "Assessment %q of feature %q failed", assess.Name(), featName)
+			}
+			if reporter := e.cfg.Reporter(); reporter != nil {
+				reporter.OnAssessmentResult(featName, assess.Name(), !tb.Failed(), time.Since(start))
+			}
+			var err error
+			for _, fn := range e.afterAssessmentFuncs {
+				if fn == nil {
+					continue
+				}
+				if ctx, err = fn(ctx, e.cfg, featName, assess.Name(), !tb.Failed()); err != nil {
+					tb.Errorf("AfterEachAssessment failure: %s", err)
+				}
+			}
+		})
+		if e.cfg.AssessmentRegex() != nil && !e.cfg.AssessmentRegex().MatchString(assess.Name()) {
+			tb.Skipf(`Skipping assessment "%s": name not matched`, assess.Name())
+		}
+		e.recordEvent(corev1.EventTypeNormal, EventReasonAssessmentStarted, "Assessment %q of feature %q started", assess.Name(), featName)
+
+		b, benchmark := tb.(*testing.B)
+		if !benchmark {
+			ctx = e.callStep(ctx, tb, assess)
+			return
+		}
+
+		b.ResetTimer()
+		benchStart := time.Now()
+		for i := 0; i < b.N && !b.Failed(); i++ {
+			ctx = e.callStep(ctx, b, assess)
+		}
+		elapsed := time.Since(benchStart)
+		b.StopTimer()
+		b.ReportMetric(elapsed.Seconds()/float64(b.N), "s/assess")
+	})
 	return ctx
 }
+
+// runFeatureFuncs executes funcs with the feature's subtest tb
+// and fails the feature on the first error. The funcs require a
+// *testing.T: they are not executed when benchmarking.
+func runFeatureFuncs(ctx context.Context, tb testing.TB, cfg *envconf.Config, funcs []types.FeatureEnvFunc, role string) context.Context {
+	t, ok := tb.(*testing.T)
+	if !ok {
+		return ctx
+	}
+
+	var err error
+	for _, fn := range funcs {
+		if fn == nil {
+			continue
+		}
+		if ctx, err = fn(ctx, t, cfg); err != nil {
+			t.Fatalf("%s failure: %s", role, err)
+		}
+	}
+	return ctx
+}
+
+// callStep calls the function of step with tb. Benchmarks, with no
+// *testing.T, call the testing.TB function of the step, which the
+// features benchmarked are checked to have (see execFeature).
+func (e *testEnv) callStep(ctx context.Context, tb testing.TB, step types.Step) context.Context {
+	if t, ok := tb.(*testing.T); ok {
+		return step.Func()(ctx, t, e.cfg)
+	}
+	return step.FuncTB()(ctx, tb, e.cfg)
+}
+
+// subtest runs fn as a subtest of tb named name, or as a
+// sub-benchmark when tb is a *testing.B.
+func subtest(tb testing.TB, name string, fn func(testing.TB)) bool {
+	if b, ok := tb.(*testing.B); ok {
+		return b.Run(name, func(b *testing.B) { fn(b) })
+	}
+	return tb.(*testing.T).Run(name, func(t *testing.T) { fn(t) })
+}
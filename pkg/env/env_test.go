@@ -18,10 +18,25 @@ package env
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/e2e-framework/klient"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
 )
 
 func TestEnv_New(t *testing.T) {
@@ -173,6 +188,26 @@ func TestEnv_Test(t *testing.T) {
 				return
 			},
 		},
+		{
+			name:     "filtered feature by labels",
+			ctx:      context.TODO(),
+			expected: 42,
+			setup: func(t *testing.T, ctx context.Context) (val int) {
+				env := NewWithConfig(envconf.New().WithLabels(map[string]string{"area": "networking", "priority": "p0"}))
+				f := features.New("test-feat").WithLabels(map[string]string{"area": "networking", "priority": "p0", "env": "kind"}).
+					Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+						val = 42
+						return ctx
+					})
+				f2 := features.New("test-feat-2").WithLabels(map[string]string{"area": "networking", "priority": "p1"}).
+					Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+						val = 42 + 1
+						return ctx
+					})
+				env.Test(t, f.Feature(), f2.Feature())
+				return
+			},
+		},
 		{
 			name:     "with before-test",
 			ctx:      context.TODO(),
@@ -379,6 +414,82 @@ func TestEnv_Test(t *testing.T) {
 				return
 			},
 		},
+		{
+			name:     "with after-each-assessment",
+			ctx:      context.TODO(),
+			expected: 2,
+			setup: func(t *testing.T, ctx context.Context) (val int) {
+				env := NewWithConfig(envconf.New().WithAssessmentRegex("add-*"))
+				env.AfterEachAssessment(func(ctx context.Context, _ *envconf.Config, featureName, assessmentName string, passed bool) (context.Context, error) {
+					if featureName != "test-feat" {
+						t.Errorf("unexpected feature name: %s", featureName)
+					}
+					if !passed {
+						t.Errorf("assessment %s unexpectedly failed", assessmentName)
+					}
+					val++
+					return ctx, nil
+				})
+				f := features.New("test-feat").
+					Assess("add-one", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+						return ctx
+					}).
+					Assess("add-two", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+						return ctx
+					}).
+					Assess("skip-me", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+						return ctx
+					})
+				env.Test(t, f.Feature())
+				return
+			},
+		},
+		{
+			name:     "features with order",
+			ctx:      context.TODO(),
+			expected: 123,
+			setup: func(t *testing.T, ctx context.Context) (val int) {
+				env := newTestEnv()
+				f1 := features.New("test-feat-1").WithOrder(2).Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					val = val*10 + 3
+					return ctx
+				})
+				f2 := features.New("test-feat-2").WithOrder(1).Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					val = val*10 + 1
+					return ctx
+				})
+				f3 := features.New("test-feat-3").WithOrder(1).Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					val = val*10 + 2
+					return ctx
+				})
+				env.Test(t, f1.Feature(), f2.Feature(), f3.Feature())
+				return
+			},
+		},
+		{
+			name:     "with before-and-after features with t",
+			ctx:      context.TODO(),
+			expected: 60,
+			setup: func(t *testing.T, ctx context.Context) (val int) {
+				env := newTestEnv()
+				env.BeforeEachFeatureWithT(func(ctx context.Context, ft *testing.T, _ *envconf.Config) (context.Context, error) {
+					if ft.Name() != t.Name()+"/test-feat" {
+						t.Errorf("unexpected test name: %s", ft.Name())
+					}
+					val += 20
+					return ctx, nil
+				}).AfterEachFeatureWithT(func(ctx context.Context, ft *testing.T, _ *envconf.Config) (context.Context, error) {
+					val -= 20
+					return ctx, nil
+				})
+				f1 := features.New("test-feat").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					val *= 4
+					return ctx
+				})
+				env.Test(t, f1.Feature())
+				return
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -420,3 +531,1008 @@ func TestEnv_Context_Propagation(t *testing.T) {
 		t.Fatalf("unexpected value %d", finalVal)
 	}
 }
+
+func TestEnv_WithGlobalLabels(t *testing.T) {
+	env := newTestEnv()
+	env.WithGlobalLabels(map[string]string{"env": "kind", "suite": "integration"})
+
+	f := features.New("test-feat").WithLabel("suite", "smoke").WithLabel("area", "networking").Feature()
+	labels := env.withGlobalLabels(f).Labels()
+
+	expected := map[string]string{"env": "kind", "suite": "smoke", "area": "networking"}
+	if len(labels) != len(expected) {
+		t.Fatalf("unexpected number of labels: %d", len(labels))
+	}
+	for k, v := range expected {
+		if labels[k] != v {
+			t.Errorf("unexpected value for label %s: %s", k, labels[k])
+		}
+	}
+
+	if len(f.Labels()) != 2 {
+		t.Error("feature labels should not be modified")
+	}
+}
+
+func TestEnv_SubsetRunRegex(t *testing.T) {
+	names := []string{"TestCreate", "TestCreate_Deployment", "TestDelete", "TestList[1]"}
+	tests := []struct {
+		name      string
+		predicate func(string) bool
+		matches   []string
+		skipped   []string
+	}{
+		{
+			name:      "select none",
+			predicate: func(string) bool { return false },
+			skipped:   names,
+		},
+		{
+			name:      "select all",
+			predicate: func(string) bool { return true },
+			matches:   names,
+		},
+		{
+			name:      "select exact names",
+			predicate: func(name string) bool { return name == "TestCreate" || name == "TestList[1]" },
+			matches:   []string{"TestCreate", "TestList[1]"},
+			skipped:   []string{"TestCreate_Deployment", "TestDelete"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			regex, err := subsetRunRegex(names, test.predicate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			re := regexp.MustCompile(regex)
+			for _, name := range test.matches {
+				if !re.MatchString(name) {
+					t.Errorf("regex %s should match %s", regex, name)
+				}
+			}
+			for _, name := range test.skipped {
+				if re.MatchString(name) {
+					t.Errorf("regex %s should not match %s", regex, name)
+				}
+			}
+		})
+	}
+}
+
+func TestEnv_OrderConflicts(t *testing.T) {
+	type ctxKey string
+	step := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }
+	feature := func(name string, order int, keys ...interface{}) types.Feature {
+		return features.New(name).WithOrder(order).WithContextKeys(keys...).Assess("check", step).Feature()
+	}
+
+	conflicts := orderConflicts(sortFeatures([]types.Feature{
+		feature("producer", 1, ctxKey("deployment")),
+		feature("consumer", 1, ctxKey("deployment"), ctxKey("service")),
+		feature("later", 2, ctxKey("deployment")),
+		feature("unrelated", 1, ctxKey("secret"), []string{"not comparable"}),
+		feature("undeclared", 1),
+	}))
+
+	expected := []string{`features "producer" and "consumer" have the same order 1 and share context key deployment`}
+	if strings.Join(conflicts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected conflicts %q, expected %q", conflicts, expected)
+	}
+}
+
+func TestEnv_TestNames(t *testing.T) {
+	// the tests of testing.M must be found with this version of Go
+	names, err := testNames(&testing.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 0 {
+		t.Errorf("unexpected test names %v", names)
+	}
+}
+
+func TestEnv_WithContextTimeout(t *testing.T) {
+	env := newTestEnv()
+	env.WithContextTimeout(10 * time.Millisecond)
+
+	deadline, ok := env.ctx.Deadline()
+	if !ok {
+		t.Fatal("context has no deadline")
+	}
+	if time.Until(deadline) > 10*time.Millisecond {
+		t.Error("unexpected context deadline", deadline)
+	}
+
+	f := features.New("test-feat").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		<-ctx.Done()
+		if ctx.Err() != context.DeadlineExceeded {
+			t.Error("unexpected context error", ctx.Err())
+		}
+		return ctx
+	})
+	env.Test(t, f.Feature())
+}
+
+func TestEnv_WithContextTimeoutFinish(t *testing.T) {
+	env := newTestEnv()
+	env.WithContextTimeout(10 * time.Millisecond)
+	envCancel := env.cancel
+	cancelled := false
+	env.cancel = func() {
+		cancelled = true
+		envCancel()
+	}
+
+	var finishErr error
+	finished := false
+	env.Finish(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+		finished = true
+		finishErr = ctx.Err()
+		if ctx.Value(&ctxTestKeyInt{}) != 42 {
+			t.Error("finish context does not hold the values set by the tests")
+		}
+		return ctx, nil
+	})
+
+	env.run(func() int {
+		<-env.ctx.Done()
+		env.ctx = context.WithValue(env.ctx, &ctxTestKeyInt{}, 42)
+		return 0
+	})
+
+	if !finished {
+		t.Fatal("finish action was not executed")
+	}
+	if finishErr != nil {
+		t.Errorf("finish action received a done context: %s", finishErr)
+	}
+	if !cancelled {
+		t.Error("environment context was not cancelled")
+	}
+}
+
+func TestEnv_TestWithDeadline(t *testing.T) {
+	env, err := NewWithContext(context.WithValue(context.TODO(), &ctxTestKeyInt{}, 42), envconf.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	f := features.New("test-feat").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		if val, ok := ctx.Value(&ctxTestKeyInt{}).(int); !ok || val != 42 {
+			t.Error("environment context value not propagated")
+		}
+		cancel()
+		<-ctx.Done()
+		return context.WithValue(ctx, &ctxTestKeyInt{}, 43)
+	})
+	env.TestWithDeadline(ctx, t, f.Feature())
+
+	envCtx := env.(*testEnv).ctx
+	if envCtx.Err() != nil {
+		t.Error("environment context should not be cancelled")
+	}
+	if val := envCtx.Value(&ctxTestKeyInt{}).(int); val != 42 {
+		t.Error("environment context not restored, unexpected value", val)
+	}
+}
+
+func TestEnv_BeforeEachTestWithCleanup(t *testing.T) {
+	var steps []string
+	t.Run("test", func(t *testing.T) {
+		env := newTestEnv()
+		env.BeforeEachTestWithCleanup(func(ctx context.Context, _ *envconf.Config, t *testing.T) (context.Context, func(), error) {
+			steps = append(steps, "before")
+			return ctx, func() { steps = append(steps, "cleanup") }, nil
+		})
+		env.AfterEachTest(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			steps = append(steps, "after")
+			return ctx, nil
+		})
+		f := features.New("test-feat").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			steps = append(steps, "assess")
+			return ctx
+		})
+		env.Test(t, f.Feature())
+	})
+
+	expected := []string{"before", "assess", "after", "cleanup"}
+	if strings.Join(steps, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected steps: %v", steps)
+	}
+}
+
+func TestEnv_TestInParallel(t *testing.T) {
+	const (
+		featureCount = 10
+		parallelism  = 5
+		sleep        = 100 * time.Millisecond
+	)
+
+	var mu sync.Mutex
+	running, maxRunning, beforeCount := 0, 0, 0
+
+	env := NewWithConfig(envconf.New().WithParallelism(parallelism))
+	env.BeforeEachFeature(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+		beforeCount++ // protected by the environment's lock
+		return ctx, nil
+	})
+
+	var feats []types.Feature
+	for i := 0; i < featureCount; i++ {
+		feats = append(feats, features.New(fmt.Sprintf("test-feat-%d", i)).Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(sleep)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return context.WithValue(ctx, &ctxTestKeyInt{}, 1)
+		}).Feature())
+	}
+
+	start := time.Now()
+	env.TestInParallel(t, feats...)
+	elapsed := time.Since(start)
+
+	if beforeCount != featureCount {
+		t.Errorf("unexpected number of BeforeEachFeature calls: %d", beforeCount)
+	}
+	if maxRunning != parallelism {
+		t.Errorf("unexpected number of concurrent features: %d", maxRunning)
+	}
+	// the elapsed time scales with featureCount / parallelism: the features
+	// cannot complete faster, and running them one after another takes
+	// featureCount * sleep, so (featureCount-1) * sleep leaves a generous slack
+	expected := sleep * featureCount / parallelism
+	if elapsed < expected || elapsed >= (featureCount-1)*sleep {
+		t.Errorf("unexpected elapsed time %s, expected at least %s and less than %s", elapsed, expected, (featureCount-1)*sleep)
+	}
+	if env.(*testEnv).ctx.Value(&ctxTestKeyInt{}) != nil {
+		t.Error("feature context values should not leak into the environment")
+	}
+}
+
+func TestEnv_FailFast(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithFailFast())
+	var executed []string
+	feature := func(name string, fail bool) types.Feature {
+		return features.New(name).Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			executed = append(executed, name)
+			if fail {
+				t.Error("feature failure")
+			}
+			return ctx
+		}).Feature()
+	}
+
+	// the tests run in their own test context so that the
+	// expected failures do not fail this test
+	matchAll := func(pat, str string) (bool, error) { return true, nil }
+	passed := testing.RunTests(matchAll, []testing.InternalTest{
+		{Name: t.Name() + "_failing", F: func(t *testing.T) {
+			env.Test(t, feature("first", true), feature("second", false))
+		}},
+		{Name: t.Name() + "_later", F: func(t *testing.T) {
+			env.Test(t, feature("third", false))
+		}},
+		{Name: t.Name() + "_parallel", F: func(t *testing.T) {
+			env.TestInParallel(t, feature("fourth", false), feature("fifth", false))
+		}},
+	})
+
+	if passed {
+		t.Error("expected the failing test to fail")
+	}
+	if strings.Join(executed, ",") != "first" {
+		t.Errorf("expected only the failing feature to be executed, got %v", executed)
+	}
+}
+
+func TestEnv_Benchmark(t *testing.T) {
+	setups, assessments := 0, 0
+	reporter := &recordingReporter{}
+	env := NewWithConfig(envconf.New().WithReporter(reporter))
+	feat := features.New("bench-feat").
+		SetupWithTB(func(ctx context.Context, tb testing.TB, _ *envconf.Config) context.Context {
+			setups++
+			return ctx
+		}).
+		AssessWithTB("noop assessment", func(ctx context.Context, tb testing.TB, _ *envconf.Config) context.Context {
+			assessments++
+			return ctx
+		}).Feature()
+
+	var n int
+	testing.Benchmark(func(b *testing.B) {
+		env.Benchmark(b, feat)
+		n = b.N
+	})
+
+	if n == 0 {
+		t.Fatal("benchmark did not run")
+	}
+	if setups != 1 {
+		t.Errorf("expected the feature setup to run once, got %d", setups)
+	}
+	if assessments <= 1 {
+		t.Errorf("expected the assessment to run b.N times, got %d", assessments)
+	}
+	if len(reporter.calls) == 0 || reporter.calls[0] != "start bench-feat" || reporter.calls[len(reporter.calls)-1] != "end bench-feat passed=true" {
+		t.Errorf("unexpected reporter calls %v", reporter.calls)
+	}
+}
+
+func TestEnv_BenchmarkFailure(t *testing.T) {
+	reporter := &recordingReporter{}
+	env := NewWithConfig(envconf.New().WithReporter(reporter))
+	teardown := false
+	feat := features.New("failing-bench").
+		AssessWithTB("fails", func(ctx context.Context, tb testing.TB, _ *envconf.Config) context.Context {
+			tb.Fatal("assessment failure")
+			return ctx
+		}).
+		TeardownWithTB(func(ctx context.Context, tb testing.TB, _ *envconf.Config) context.Context {
+			teardown = true
+			return ctx
+		}).Feature()
+
+	testing.Benchmark(func(b *testing.B) {
+		env.Benchmark(b, feat)
+	})
+
+	if !teardown {
+		t.Error("teardown was not executed after the assessment failure")
+	}
+	expected := "end failing-bench passed=false"
+	if len(reporter.calls) == 0 || reporter.calls[len(reporter.calls)-1] != expected {
+		t.Errorf("unexpected reporter calls %v, expected last call %q", reporter.calls, expected)
+	}
+}
+
+func TestEnv_BenchmarkTestFlags(t *testing.T) {
+	// the test flags a benchmark is run with must not filter out its steps
+	tests := []struct {
+		name  string
+		flag  string
+		value string
+	}{
+		{name: "run excluding tests", flag: "test.run", value: "^$"},
+		{name: "grouped skip", flag: "test.skip", value: "(Zzz|Yyy)"},
+		{name: "skip with subtest", flag: "test.skip", value: "Zzz/Yyy"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := flag.Lookup(test.flag)
+			if f == nil {
+				t.Skipf("flag -%s not supported", test.flag)
+			}
+			previous := f.Value.String()
+			if err := f.Value.Set(test.value); err != nil {
+				t.Fatal(err)
+			}
+			defer f.Value.Set(previous)
+
+			var setups, assessments int
+			feat := features.New("bench-feat").
+				SetupWithTB(func(ctx context.Context, tb testing.TB, _ *envconf.Config) context.Context {
+					setups++
+					return ctx
+				}).
+				AssessWithTB("noop assessment", func(ctx context.Context, tb testing.TB, _ *envconf.Config) context.Context {
+					assessments++
+					return ctx
+				}).Feature()
+
+			result := testing.Benchmark(func(b *testing.B) {
+				newTestEnv().Benchmark(b, feat)
+			})
+
+			if setups != 1 || assessments == 0 {
+				t.Errorf("expected the steps to run, got %d setup(s) and %d assessment(s)", setups, assessments)
+			}
+			if result.N == 0 {
+				t.Error("benchmark did not run")
+			}
+		})
+	}
+}
+
+func TestEnv_BenchmarkTestingTSteps(t *testing.T) {
+	// features with steps requiring a *testing.T are skipped
+	var setups, assessments int
+	feat := features.New("test-only").
+		SetupWithTB(func(ctx context.Context, tb testing.TB, _ *envconf.Config) context.Context {
+			setups++
+			return ctx
+		}).
+		Assess("requires t", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			assessments++
+			return ctx
+		}).Feature()
+
+	testing.Benchmark(func(b *testing.B) {
+		newTestEnv().Benchmark(b, feat)
+	})
+
+	if setups != 0 || assessments != 0 {
+		t.Errorf("expected the feature to be skipped, got %d setup(s) and %d assessment(s)", setups, assessments)
+	}
+}
+
+func TestEnv_Describe(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithLabels(map[string]string{"env": "test"}))
+	noop := func(ctx context.Context, _ *envconf.Config) (context.Context, error) { return ctx, nil }
+	env.Setup(noop, noop).BeforeEachTest(noop).Finish(noop)
+
+	step := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }
+	selected := features.New("selected").WithLabel("env", "test").Setup(step).Assess("check", step).Feature()
+	skipped := features.New("skipped").WithLabel("env", "prod").Assess("check", step).Feature()
+
+	desc := env.Describe(selected, skipped)
+	for _, expected := range []string{
+		"Setup: 2 func(s)",
+		"BeforeEachTest: 1 func(s)",
+		"Finish: 1 func(s)",
+		"selected [env=test]\n",
+		"    assess: check\n",
+		"skipped [env=prod] (skipped: label env=test not matched)",
+	} {
+		if !strings.Contains(desc, expected) {
+			t.Errorf("description does not contain %q:\n%s", expected, desc)
+		}
+	}
+}
+
+func TestEnv_DescribeSkipReasonOrder(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithLabels(map[string]string{"env": "test", "arch": "arm64", "zone": "a"}))
+	step := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }
+	feature := features.New("unlabeled").Assess("check", step).Feature()
+
+	// the first label, in key order, is reported whatever the map iteration order
+	for i := 0; i < 20; i++ {
+		expected := "unlabeled (skipped: label arch=arm64 not matched)"
+		if desc := env.Describe(feature); !strings.Contains(desc, expected) {
+			t.Fatalf("description does not contain %q:\n%s", expected, desc)
+		}
+	}
+}
+
+func TestEnv_Middleware(t *testing.T) {
+	var calls []string
+	middleware := func(name string) func(Func) Func {
+		return func(next Func) Func {
+			return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+				calls = append(calls, name+":before")
+				ctx, err := next(ctx, cfg)
+				calls = append(calls, name+":after")
+				return ctx, err
+			}
+		}
+	}
+
+	env := newTestEnv()
+	env.Middleware(middleware("outer"))
+	env.Setup(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+		calls = append(calls, "setup")
+		return ctx, nil
+	})
+	env.Middleware(middleware("inner"))
+
+	for _, action := range env.getSetupActions() {
+		if _, err := action.run(env.ctx, env.cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := "outer:before,inner:before,setup,inner:after,outer:after"
+	if strings.Join(calls, ",") != expected {
+		t.Errorf("unexpected call order %v, expected %s", calls, expected)
+	}
+}
+
+func TestEnv_Clone(t *testing.T) {
+	noop := func(ctx context.Context, _ *envconf.Config) (context.Context, error) { return ctx, nil }
+	env := NewWithConfig(envconf.New().WithNamespace("original")).Setup(noop)
+
+	clone := env.Clone().Setup(noop)
+	clone.(*testEnv).cfg.WithNamespace("clone")
+
+	if len(env.(*testEnv).getSetupActions()) != 1 {
+		t.Errorf("setup added to the clone should not appear in the original environment")
+	}
+	if len(clone.(*testEnv).getSetupActions()) != 2 {
+		t.Errorf("clone should have the original setup and its own")
+	}
+	if env.(*testEnv).cfg.Namespace() != "original" {
+		t.Errorf("changes to the clone config should not affect the original environment")
+	}
+}
+
+func TestEnv_ParallelAssessments(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		running     int
+		maxRunning  int
+		parallelRan int
+	)
+	parallel := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		parallelRan++
+		mu.Unlock()
+		return ctx
+	}
+
+	f := features.New("parallel-assessments").
+		Assess("sequential-before", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			return ctx
+		}).
+		Assess("parallel-1", parallel).Parallel().
+		Assess("parallel-2", parallel).Parallel().
+		AssessInParallel("parallel-3", parallel).
+		Assess("sequential-after", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			mu.Lock()
+			defer mu.Unlock()
+			if parallelRan != 3 {
+				t.Errorf("sequential assessment started before parallel assessments completed: %d of 3 done", parallelRan)
+			}
+			return ctx
+		}).Feature()
+
+	newTestEnv().Test(t, f)
+
+	// parallel subtests are limited by the -test.parallel flag
+	expected := 3
+	if n, err := strconv.Atoi(flag.Lookup("test.parallel").Value.String()); err == nil && n < expected {
+		expected = n
+	}
+	if maxRunning != expected {
+		t.Errorf("expected %d parallel assessments to run concurrently, max concurrency was %d", expected, maxRunning)
+	}
+}
+
+func TestEnv_ParallelAssessmentFatal(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		executed []string
+	)
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		executed = append(executed, name)
+	}
+	f := features.New("parallel-fatal").
+		AssessInParallel("fatal", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			t.Fatal("parallel assessment failure")
+			record("fatal")
+			return ctx
+		}).
+		AssessInParallel("passing", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			record("passing")
+			return ctx
+		}).
+		Assess("sequential", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			record("sequential")
+			return ctx
+		}).Feature()
+
+	// the test runs in its own test context so that the
+	// expected failure does not fail this test
+	matchAll := func(pat, str string) (bool, error) { return true, nil }
+	passed := testing.RunTests(matchAll, []testing.InternalTest{
+		{Name: t.Name() + "_feature", F: func(t *testing.T) { newTestEnv().Test(t, f) }},
+	})
+
+	if passed {
+		t.Error("expected the fatal parallel assessment to fail the test")
+	}
+	if strings.Join(executed, ",") != "passing,sequential" {
+		t.Errorf("expected only the fatal assessment to stop, executed %v", executed)
+	}
+}
+
+func TestEnv_WithEventRecorder(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	env := NewWithConfig(envconf.New().WithNamespace("events")).WithEventRecorder(recorder)
+
+	f := features.New("recorded").
+		Assess("first", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }).
+		Assess("second", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }).
+		Feature()
+	env.Test(t, f)
+	close(recorder.Events)
+
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	expected := []string{
+		`Normal FeatureStarted Feature "recorded" started`,
+		`Normal AssessmentStarted Assessment "first" of feature "recorded" started`,
+		`Normal AssessmentStarted Assessment "second" of feature "recorded" started`,
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected events:\n%s\nexpected:\n%s", strings.Join(events, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestEnv_WithEventSink(t *testing.T) {
+	client := klient.NewFakeClient()
+	env := newTestEnv()
+	env.WithEventSink(client, "events")
+	env.Setup(func(ctx context.Context, _ *envconf.Config) (context.Context, error) { return ctx, nil })
+
+	f := features.New("published").
+		Assess("check", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }).
+		Feature()
+	env.run(func() int {
+		env.Test(t, f)
+		return 0
+	})
+
+	// the pending events are published once run returns
+	var events corev1.EventList
+	if err := client.Resources("events").List(context.TODO(), &events); err != nil {
+		t.Fatal(err)
+	}
+	var reasons []string
+	for _, event := range events.Items {
+		if event.Source.Component != eventComponent || event.InvolvedObject.Name != "events" {
+			t.Errorf("unexpected event source %v or object %v", event.Source, event.InvolvedObject)
+		}
+		reasons = append(reasons, event.Reason)
+	}
+	sort.Strings(reasons)
+	expected := []string{EventReasonAssessmentStarted, EventReasonFeatureStarted, EventReasonSetupStarted}
+	if strings.Join(reasons, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected events %v, expected %v", reasons, expected)
+	}
+
+	// events recorded once the sink is shut down are dropped
+	env.recordEvent(corev1.EventTypeNormal, EventReasonSetupStarted, "late")
+}
+
+func TestEnv_RunTimeout(t *testing.T) {
+	type ctxKey string
+
+	var finishErr error
+	env := NewWithConfig(envconf.New().WithTimeout(50 * time.Millisecond)).
+		Setup(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			return context.WithValue(ctx, ctxKey("cluster"), "kind"), nil
+		}).
+		Finish(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			if ctx.Value(ctxKey("cluster")) != "kind" {
+				finishErr = fmt.Errorf("finish context lost the setup values")
+			}
+			if ctx.Err() != nil {
+				finishErr = fmt.Errorf("finish context is done: %w", ctx.Err())
+			}
+			return ctx, nil
+		})
+
+	start := time.Now()
+	code := env.(*testEnv).run(func() int {
+		time.Sleep(5 * time.Second)
+		return 0
+	})
+	if code == 0 {
+		t.Error("expected a non-zero exit code when the suite times out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("run did not return on timeout, took %s", elapsed)
+	}
+	if finishErr != nil {
+		t.Error(finishErr)
+	}
+
+	code = NewWithConfig(envconf.New().WithTimeout(time.Second)).(*testEnv).run(func() int { return 3 })
+	if code != 3 {
+		t.Errorf("expected the tests exit code 3, got %d", code)
+	}
+}
+
+func TestEnv_FeatureTimeout(t *testing.T) {
+	type ctxKey string
+
+	env := newTestEnv()
+	hasDeadline := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("feature step context has no deadline")
+		}
+		return context.WithValue(ctx, ctxKey("step"), "done")
+	}
+	f := features.New("timeout").
+		WithTimeout(time.Minute).
+		Setup(hasDeadline).
+		Assess("assess", hasDeadline).
+		Teardown(hasDeadline).
+		Feature()
+
+	env.Test(t, f)
+
+	if _, ok := env.ctx.Deadline(); ok {
+		t.Error("feature deadline applied to the environment context")
+	}
+	if env.ctx.Err() != nil {
+		t.Error("environment context done after the feature:", env.ctx.Err())
+	}
+	if env.ctx.Value(ctxKey("step")) != "done" {
+		t.Error("feature context values not propagated")
+	}
+}
+
+func TestEnv_SetupWithRollback(t *testing.T) {
+	var calls []string
+	record := func(call string, err error) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			calls = append(calls, call)
+			return ctx, err
+		}
+	}
+
+	env := newTestEnv()
+	env.SetupWithRollback(record("create cluster", nil), record("delete cluster", nil)).
+		Setup(record("load image", nil)).
+		SetupWithRollback(record("create namespace", nil), record("delete namespace", fmt.Errorf("namespace not found"))).
+		SetupWithRollback(record("install chart", fmt.Errorf("chart not found")), record("uninstall chart", nil)).
+		SetupWithRollback(record("deploy", nil), record("undeploy", nil))
+
+	if err := env.runSetupActions(); err == nil {
+		t.Fatal("expected the setup error")
+	}
+
+	expected := []string{"create cluster", "load image", "create namespace", "install chart", "delete namespace", "delete cluster"}
+	if strings.Join(calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+
+	calls = nil
+	if err := newTestEnv().SetupWithRollback(record("create cluster", nil), record("delete cluster", nil)).(*testEnv).runSetupActions(); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("rollback executed after a successful setup: %v", calls)
+	}
+}
+
+func TestEnv_RunInterrupted(t *testing.T) {
+	var finished bool
+	var finishErr error
+	env := New().Finish(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+		finished = true
+		if ctx.Err() != nil {
+			finishErr = fmt.Errorf("finish context is done: %w", ctx.Err())
+		}
+		return ctx, nil
+	}).(*testEnv)
+
+	// the signals are delivered to the process, including this package's
+	// TestMain environment, so they are simulated
+	signals := make(chan os.Signal, 1)
+	notifyContext = func(parent context.Context, sigs ...os.Signal) (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(parent)
+		go func() {
+			for sig := range signals {
+				for _, s := range sigs {
+					if s == sig {
+						cancel()
+					}
+				}
+			}
+		}()
+		return ctx, cancel
+	}
+	defer func() { notifyContext = signal.NotifyContext }()
+
+	var testsStopped bool
+	code := env.run(func() int {
+		signals <- os.Interrupt
+		close(signals)
+		select {
+		case <-env.ctx.Done():
+			testsStopped = true
+		case <-time.After(5 * time.Second):
+		}
+		return 1
+	})
+
+	if code != interruptExitCode {
+		t.Errorf("expected exit code %d, got %d", interruptExitCode, code)
+	}
+	if !testsStopped {
+		t.Error("environment context not cancelled on interrupt")
+	}
+	if !finished {
+		t.Error("finish actions not executed on interrupt")
+	}
+	if finishErr != nil {
+		t.Error(finishErr)
+	}
+}
+
+// recordingReporter records the calls to its methods
+type recordingReporter struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recordingReporter) OnFeatureStart(name string) {
+	r.record(fmt.Sprintf("start %s", name))
+}
+
+func (r *recordingReporter) OnAssessmentResult(feature, assessment string, passed bool, _ time.Duration) {
+	r.record(fmt.Sprintf("assessment %s/%s passed=%t", feature, assessment, passed))
+}
+
+func (r *recordingReporter) OnFeatureEnd(name string, passed bool) {
+	r.record(fmt.Sprintf("end %s passed=%t", name, passed))
+}
+
+func (r *recordingReporter) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func TestEnv_Reporter(t *testing.T) {
+	reporter := &recordingReporter{}
+	env := NewWithConfig(envconf.New().WithReporter(reporter))
+
+	f := features.New("reported").
+		Assess("first", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }).
+		Assess("second", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }).
+		Feature()
+	env.Test(t, f)
+
+	expected := []string{
+		"start reported",
+		"assessment reported/first passed=true",
+		"assessment reported/second passed=true",
+		"end reported passed=true",
+	}
+	if strings.Join(reporter.calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected reporter calls %v, expected %v", reporter.calls, expected)
+	}
+}
+
+func TestEnv_WithSuiteTimeout(t *testing.T) {
+	var logs strings.Builder
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	env := New().WithSuiteTimeout(100 * time.Millisecond)
+	f := features.New("slow").
+		Assess("sleep", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			time.Sleep(500 * time.Millisecond)
+			return ctx
+		}).Feature()
+
+	done := make(chan struct{})
+	code := env.(*testEnv).run(func() int {
+		defer close(done)
+		env.Test(t, f)
+		return 0
+	})
+	<-done
+
+	if code == 0 {
+		t.Error("expected a non-zero exit code when the suite times out")
+	}
+	if !strings.Contains(logs.String(), `Feature "slow" timed out`) {
+		t.Errorf("expected feature slow to be logged as timed out, got logs:\n%s", logs.String())
+	}
+}
+
+func TestEnv_FeatureTable(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		names []string
+		sum   int
+	)
+	assess := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, t.Name())
+		sum += features.ParamsFromContext(ctx).(int)
+		return ctx
+	}
+
+	var cases []features.FeatureCase
+	for i := 1; i <= 5; i++ {
+		cases = append(cases, features.FeatureCase{Name: fmt.Sprintf("case-%d", i), Params: i, Assess: assess})
+	}
+	newTestEnv().Test(t, features.TableFeatures(features.Table("table", cases))...)
+
+	if len(names) != 5 {
+		t.Fatalf("expected 5 cases to be tested, got %d", len(names))
+	}
+	for i, name := range names {
+		expected := fmt.Sprintf("%s/table/case-%d/case-%d", t.Name(), i+1, i+1)
+		if name != expected {
+			t.Errorf("unexpected subtest name %s, expected %s", name, expected)
+		}
+	}
+	if sum != 15 {
+		t.Errorf("expected each case to receive its params, got sum %d", sum)
+	}
+}
+
+func TestEnv_FeatureTableParamsScope(t *testing.T) {
+	type ctxKey struct{}
+	var seen []interface{}
+	step := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		seen = append(seen, features.ParamsFromContext(ctx))
+		return context.WithValue(ctx, ctxKey{}, "set by case")
+	}
+	cases := []features.FeatureCase{{Name: "case-1", Params: 1, Setup: step, Assess: step}, {Name: "case-2", Params: 2, Assess: step}}
+	table := features.TableFeatures(features.Table("table", cases))
+
+	var later []interface{}
+	after := features.New("after").Assess("params", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		later = append(later, features.ParamsFromContext(ctx), ctx.Value(ctxKey{}))
+		return ctx
+	}).Feature()
+
+	env := newTestEnv()
+	env.Test(t, append(table, after)...)
+
+	if fmt.Sprint(seen) != "[1 1 2]" {
+		t.Errorf("expected the case steps to receive params [1 1 2], got %v", seen)
+	}
+	// values set by the steps are kept, the params of the last case are not
+	if len(later) != 2 || later[0] != nil || later[1] != "set by case" {
+		t.Errorf("expected the later feature to see no params and the values of the cases, got %v", later)
+	}
+	if params := features.ParamsFromContext(env.ctx); params != nil {
+		t.Errorf("expected no params in the environment context, got %v", params)
+	}
+}
+
+func TestEnv_FeatureBaseContext(t *testing.T) {
+	type ctxKey string
+
+	env := newTestEnv()
+	env.ctx = context.WithValue(env.ctx, ctxKey("cluster"), "primary")
+
+	var clusters []interface{}
+	recordCluster := func(ctx context.Context, _ *testing.T, _ *envconf.Config) context.Context {
+		clusters = append(clusters, ctx.Value(ctxKey("cluster")))
+		return context.WithValue(ctx, ctxKey("feature"), "updated")
+	}
+	remote := features.New("remote").
+		WithBaseContext(context.WithValue(context.Background(), ctxKey("cluster"), "remote")).
+		Assess("remote cluster", recordCluster).
+		Feature()
+	local := features.New("local").Assess("local cluster", recordCluster).Feature()
+
+	env.Test(t, remote, local)
+
+	if len(clusters) != 2 || clusters[0] != "remote" || clusters[1] != "primary" {
+		t.Errorf("unexpected clusters in feature contexts: %v", clusters)
+	}
+	if env.ctx.Value(ctxKey("cluster")) != "primary" {
+		t.Error("feature base context replaced the environment context")
+	}
+	if env.ctx.Value(ctxKey("feature")) != "updated" {
+		t.Error("context of the feature without base context not propagated")
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// eventComponent is the source component of the published events
+const eventComponent = "e2e-framework"
+
+// eventFlushTimeout is the time given to the event sink to
+// publish the pending events once the test suite completes
+const eventFlushTimeout = 10 * time.Second
+
+// Reasons of the events published by the environment
+const (
+	EventReasonSetupStarted      = "SetupStarted"
+	EventReasonSetupFailed       = "SetupFailed"
+	EventReasonFeatureStarted    = "FeatureStarted"
+	EventReasonFeatureFailed     = "FeatureFailed"
+	EventReasonAssessmentStarted = "AssessmentStarted"
+	EventReasonAssessmentFailed  = "AssessmentFailed"
+)
+
+// WithEventRecorder sets the recorder used to publish Kubernetes events when
+// the environment setup, each feature and each assessment start, and when
+// they fail. Events are recorded for the namespace set in the environment
+// config, or the default namespace when not set.
+func (e *testEnv) WithEventRecorder(recorder record.EventRecorder) types.Environment {
+	e.shutdownEventSink()
+	e.recorder = recorder
+	return e
+}
+
+// WithEventSink publishes the environment events (see WithEventRecorder) to
+// namespace, using a recorder that creates the events with the client. The
+// pending events are published before Env.Run returns.
+func (e *testEnv) WithEventSink(client klient.Client, namespace string) types.Environment {
+	e.shutdownEventSink()
+	sink := &eventSink{client: client, broadcaster: record.NewBroadcaster()}
+	sink.broadcaster.StartEventWatcher(sink.create)
+	e.eventSink = sink
+	e.recorder = sink.broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventComponent})
+	e.eventNamespace = namespace
+	return e
+}
+
+// shutdownEventSink publishes the pending events of the event sink,
+// if any, and shuts down its broadcaster.
+func (e *testEnv) shutdownEventSink() {
+	if e.eventSink != nil {
+		e.eventSink.shutdown()
+	}
+}
+
+// eventSink creates, with a client, the events recorded
+// through its broadcaster
+type eventSink struct {
+	client      klient.Client
+	broadcaster record.EventBroadcaster
+	// pending counts the events recorded but not created yet
+	pending sync.WaitGroup
+	mu      sync.Mutex
+	stopped bool
+}
+
+// add counts an event about to be recorded, it returns
+// false once the sink is shut down
+func (s *eventSink) add() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return false
+	}
+	s.pending.Add(1)
+	return true
+}
+
+// create creates an event received from the broadcaster
+func (s *eventSink) create(event *corev1.Event) {
+	defer s.pending.Done()
+	if err := s.client.Resources().Create(context.TODO(), event); err != nil {
+		log.Printf("event sink: failed to publish event %s: %s", event.Reason, err)
+	}
+}
+
+// shutdown waits, for up to eventFlushTimeout, for the pending
+// events to be created and shuts down the broadcaster
+func (s *eventSink) shutdown() {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.stopped = true
+	s.mu.Unlock()
+	if stopped {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(eventFlushTimeout):
+		log.Printf("event sink: pending events not published within %s", eventFlushTimeout)
+	}
+	s.broadcaster.Shutdown()
+}
+
+// recordEvent publishes an event, when a recorder is set, about the
+// namespace of the environment.
+func (e *testEnv) recordEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	if e.recorder == nil {
+		return
+	}
+	if e.eventSink != nil && !e.eventSink.add() {
+		return
+	}
+
+	namespace := e.eventNamespace
+	if namespace == "" {
+		namespace = e.cfg.Namespace()
+	}
+	if namespace == "" {
+		namespace = corev1.NamespaceDefault
+	}
+
+	ref := &corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: namespace, Namespace: namespace}
+	e.recorder.Eventf(ref, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// WithRetry wraps fn so that it is retried up to n times, waiting delay
+// between attempts: fn is called at most n+1 times. The error from the
+// last attempt is returned if all retries are exhausted.
+func WithRetry(n int, delay time.Duration, fn Func) Func {
+	return retry(n, delay, 1, fn)
+}
+
+// WithRetryExponential wraps fn so that it is retried up to n times, like
+// WithRetry. The delay between attempts starts at base and doubles after
+// each attempt.
+func WithRetryExponential(n int, base time.Duration, fn Func) Func {
+	return retry(n, base, 2, fn)
+}
+
+func retry(n int, delay time.Duration, factor int, fn Func) Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		var err error
+		for retries := 0; ; retries++ {
+			var result context.Context
+			result, err = fn(ctx, cfg)
+			if err == nil {
+				return result, nil
+			}
+			if retries >= n {
+				break
+			}
+
+			klog.Infof("env func failed, retry %d of %d in %s: %s", retries+1, n, delay, err)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx, ctx.Err()
+			}
+			delay *= time.Duration(factor)
+		}
+		return ctx, err
+	}
+}
+
+// When wraps fn so that it is only called when condition returns true,
+// evaluated each time the wrapped func is executed. Otherwise, the
+// wrapped func returns the context unchanged.
+func When(condition func(context.Context, *envconf.Config) bool, fn Func) Func {
+	if fn == nil {
+		return nil
+	}
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if !condition(ctx, cfg) {
+			return ctx, nil
+		}
+		return fn(ctx, cfg)
+	}
+}
+
+// WithTimeout wraps fn so that it is called with a context that expires
+// after d. If fn does not return within d, the wrapped func returns an
+// error wrapping context.DeadlineExceeded without waiting for fn. Only the
+// context passed to fn is cancelled, the environment context is not.
+func WithTimeout(d time.Duration, fn Func) Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		timeoutCtx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		type result struct {
+			ctx context.Context
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			resultCtx, err := fn(timeoutCtx, cfg)
+			done <- result{ctx: resultCtx, err: err}
+		}()
+
+		select {
+		case res := <-done:
+			if res.ctx == nil {
+				return ctx, res.err
+			}
+			return &valuesContext{Context: ctx, values: res.ctx}, res.err
+		case <-timeoutCtx.Done():
+			if ctx.Err() != nil {
+				return ctx, ctx.Err()
+			}
+			return ctx, fmt.Errorf("env func did not complete within %s: %w", d, context.DeadlineExceeded)
+		}
+	}
+}
+
+// valuesContext looks values up in the context returned by a wrapped func,
+// which may be derived from a cancelled context, while its deadline and
+// cancellation come from the embedded context.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c *valuesContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
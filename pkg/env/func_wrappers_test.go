@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		wrap       func(Func) Func
+		failures   int
+		expected   int
+		shouldFail bool
+	}{
+		{
+			name:     "succeeds on third attempt",
+			wrap:     func(fn Func) Func { return WithRetry(3, time.Millisecond, fn) },
+			failures: 2,
+			expected: 3,
+		},
+		{
+			name:     "succeeds on last retry",
+			wrap:     func(fn Func) Func { return WithRetry(2, time.Millisecond, fn) },
+			failures: 2,
+			expected: 3,
+		},
+		{
+			name:       "retries exhausted",
+			wrap:       func(fn Func) Func { return WithRetry(2, time.Millisecond, fn) },
+			failures:   5,
+			expected:   3,
+			shouldFail: true,
+		},
+		{
+			name:       "no retry",
+			wrap:       func(fn Func) Func { return WithRetry(0, time.Millisecond, fn) },
+			failures:   1,
+			expected:   1,
+			shouldFail: true,
+		},
+		{
+			name:     "exponential succeeds on last retry",
+			wrap:     func(fn Func) Func { return WithRetryExponential(2, time.Millisecond, fn) },
+			failures: 2,
+			expected: 3,
+		},
+		{
+			name:     "exponential succeeds on third attempt",
+			wrap:     func(fn Func) Func { return WithRetryExponential(3, time.Millisecond, fn) },
+			failures: 2,
+			expected: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			fn := test.wrap(func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+				calls++
+				if calls <= test.failures {
+					return ctx, errors.New("transient error")
+				}
+				return ctx, nil
+			})
+
+			_, err := fn(context.TODO(), envconf.New())
+			if test.shouldFail && err == nil {
+				t.Fatal("expected an error after retries were exhausted")
+			}
+			if !test.shouldFail && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if calls != test.expected {
+				t.Errorf("expected %d attempts, got %d", test.expected, calls)
+			}
+		})
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx := context.WithValue(context.TODO(), &ctxTestKeyInt{}, 1)
+
+	sleeping := WithTimeout(time.Second, func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		time.Sleep(5 * time.Second)
+		return ctx, nil
+	})
+	start := time.Now()
+	if _, err := sleeping(ctx, envconf.New()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("wrapped func did not time out, took %s", elapsed)
+	}
+	if ctx.Err() != nil {
+		t.Error("outer context should not be cancelled")
+	}
+
+	quick := WithTimeout(time.Second, func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		return context.WithValue(ctx, &ctxTestKeyInt{}, 2), nil
+	})
+	result, err := quick(ctx, envconf.New())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Err() != nil {
+		t.Error("returned context should not be cancelled")
+	}
+	if val := result.Value(&ctxTestKeyInt{}).(int); val != 2 {
+		t.Errorf("expected context value 2, got %d", val)
+	}
+}
+
+func TestEnv_ConditionalActions(t *testing.T) {
+	var calls []string
+	record := func(call string) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			calls = append(calls, call)
+			return ctx, nil
+		}
+	}
+	always := func(context.Context, *envconf.Config) bool { return true }
+	never := func(context.Context, *envconf.Config) bool { return false }
+
+	env := newTestEnv()
+	env.SetupIf(always, record("setup")).
+		SetupIf(never, record("skipped setup")).
+		BeforeEachTestIf(always, record("before test")).
+		BeforeEachTestIf(never, record("skipped before test")).
+		AfterEachTestIf(always, record("after test")).
+		AfterEachTestIf(never, record("skipped after test")).
+		FinishIf(always, record("finish")).
+		FinishIf(never, record("skipped finish"))
+
+	env.run(func() int {
+		env.Test(t, features.New("feature").Assess("noop", func(ctx context.Context, _ *testing.T, _ *envconf.Config) context.Context {
+			return ctx
+		}).Feature())
+		return 0
+	})
+
+	expected := []string{"setup", "before test", "after test", "finish"}
+	if strings.Join(calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+}
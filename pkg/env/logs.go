@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// ArtifactDirEnvVar is the environment variable that sets the directory the
+// cluster logs are exported to when the test suite fails. When not set, the
+// logs are exported to a new temporary directory.
+const ArtifactDirEnvVar = "E2E_ARTIFACT_DIR"
+
+// LogExporter is implemented by the clusters that can export their
+// logs (i.e. kubelet, API server) to a directory, like kind.Cluster.
+type LogExporter interface {
+	ExportLogs(destDir string) error
+}
+
+type logExportersKey struct{}
+
+// namedLogExporter is a LogExporter registered under its cluster name
+type namedLogExporter struct {
+	name     string
+	exporter LogExporter
+}
+
+// WithLogExporter returns a copy of ctx in which the exporter of the named
+// cluster is registered. When the test suite fails, the logs of the registered
+// clusters are exported, before the finish actions run, to a subdirectory named
+// after the cluster of the artifact directory (see ArtifactDirEnvVar).
+func WithLogExporter(ctx context.Context, name string, exporter LogExporter) context.Context {
+	exporters, _ := ctx.Value(logExportersKey{}).([]namedLogExporter)
+	exporters = append(append([]namedLogExporter(nil), exporters...), namedLogExporter{name: name, exporter: exporter})
+	return context.WithValue(ctx, logExportersKey{}, exporters)
+}
+
+// exportLogs exports the logs of the clusters registered in ctx
+// with WithLogExporter. Upon error, it logs and continues.
+func exportLogs(ctx context.Context) {
+	exporters, _ := ctx.Value(logExportersKey{}).([]namedLogExporter)
+	if len(exporters) == 0 {
+		return
+	}
+
+	dir := os.Getenv(ArtifactDirEnvVar)
+	if dir == "" {
+		var err error
+		if dir, err = ioutil.TempDir("", "e2e-logs"); err != nil {
+			log.Printf("Failed to export cluster logs: %s", err)
+			return
+		}
+	}
+
+	for _, e := range exporters {
+		destDir := filepath.Join(dir, e.name)
+		log.Printf("Exporting logs of cluster %s to %s", e.name, destDir)
+		if err := e.exporter.ExportLogs(destDir); err != nil {
+			log.Printf("Failed to export logs of cluster %s: %s", e.name, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// dirLogExporter records the directories it exports logs to
+type dirLogExporter struct {
+	dirs []string
+}
+
+func (e *dirLogExporter) ExportLogs(destDir string) error {
+	e.dirs = append(e.dirs, destDir)
+	return nil
+}
+
+func TestEnv_ExportLogsOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Setenv(ArtifactDirEnvVar, dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(ArtifactDirEnvVar)
+
+	tests := []struct {
+		name     string
+		exitCode int
+		expected []string
+	}{
+		{name: "passed", exitCode: 0},
+		{name: "failed", exitCode: 1, expected: []string{filepath.Join(dir, "primary"), filepath.Join(dir, "secondary")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			primary, secondary := &dirLogExporter{}, &dirLogExporter{}
+			env := New().Setup(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+				ctx = WithLogExporter(ctx, "primary", primary)
+				return WithLogExporter(ctx, "secondary", secondary), nil
+			}).(*testEnv)
+
+			if code := env.run(func() int { return test.exitCode }); code != test.exitCode {
+				t.Errorf("expected exit code %d, got %d", test.exitCode, code)
+			}
+
+			exported := append(primary.dirs, secondary.dirs...)
+			if len(exported) != len(test.expected) {
+				t.Fatalf("expected logs exported to %v, got %v", test.expected, exported)
+			}
+			for i := range exported {
+				if exported[i] != test.expected[i] {
+					t.Errorf("expected logs exported to %v, got %v", test.expected, exported)
+				}
+			}
+		})
+	}
+}
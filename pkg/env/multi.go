@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// ClusterKey is the context key of the name of the cluster an environment
+// of a MultiClusterEnvironment targets. Its value, a string, is available to
+// the environment funcs and feature steps, i.e. ctx.Value(env.ClusterKey{}).
+type ClusterKey struct{}
+
+// MultiClusterEnvironment groups the environments of several clusters, i.e. to
+// test federation or disaster recovery. Each environment keeps its own context
+// and configuration and is registered under the name of its cluster.
+type MultiClusterEnvironment struct {
+	names    []string
+	clusters map[string]*testEnv
+}
+
+// NewMultiCluster creates a multi-cluster environment with no cluster.
+func NewMultiCluster() *MultiClusterEnvironment {
+	return &MultiClusterEnvironment{clusters: make(map[string]*testEnv)}
+}
+
+// AddCluster registers env, created with New, NewWithConfig or
+// NewWithContext, as the environment of the named cluster and adds
+// the name to its context (see ClusterKey).
+func (m *MultiClusterEnvironment) AddCluster(name string, env types.Environment) *MultiClusterEnvironment {
+	e, ok := env.(*testEnv)
+	if !ok {
+		panic(fmt.Sprintf("cluster %s: unsupported environment type %T", name, env))
+	}
+	if _, exists := m.clusters[name]; !exists {
+		m.names = append(m.names, name)
+	}
+	e.ctx = context.WithValue(e.ctx, ClusterKey{}, name)
+	m.clusters[name] = e
+	return m
+}
+
+// ForCluster returns the environment of the named cluster,
+// or nil when no such cluster was added.
+func (m *MultiClusterEnvironment) ForCluster(name string) types.Environment {
+	if env, ok := m.clusters[name]; ok {
+		return env
+	}
+	return nil
+}
+
+// TestOnAll tests the features against each cluster, in the order the clusters
+// were added, using a subtest named after the cluster for each of them.
+func (m *MultiClusterEnvironment) TestOnAll(t *testing.T, features ...types.Feature) {
+	for _, name := range m.names {
+		env := m.clusters[name]
+		t.Run(name, func(t *testing.T) {
+			env.Test(t, features...)
+		})
+	}
+}
+
+// Run launches the test suite from a TestMain function, like Env.Run. The
+// setup operations of the clusters' environments are executed in the order
+// the clusters were added and their finish operations in reverse order.
+func (m *MultiClusterEnvironment) Run(tm *testing.M) int {
+	return m.run(tm.Run)
+}
+
+// run nests the run of each cluster's environment in the
+// run of the environment of the cluster added before it
+func (m *MultiClusterEnvironment) run(runTests func() int) int {
+	for i := len(m.names) - 1; i >= 0; i-- {
+		env, next := m.clusters[m.names[i]], runTests
+		runTests = func() int { return env.run(next) }
+	}
+	return runTests()
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestMultiClusterEnvironment(t *testing.T) {
+	var calls []string
+	record := func(step string) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			calls = append(calls, step+" "+ctx.Value(ClusterKey{}).(string))
+			return ctx, nil
+		}
+	}
+
+	multi := NewMultiCluster().
+		AddCluster("primary", New()).
+		AddCluster("secondary", New())
+	for _, name := range []string{"primary", "secondary"} {
+		multi.ForCluster(name).Setup(record("setup")).Finish(record("finish"))
+	}
+	if multi.ForCluster("unknown") != nil {
+		t.Error("expected no environment for an unknown cluster")
+	}
+
+	f := features.New("replicated").Assess("cluster", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		calls = append(calls, "test "+ctx.Value(ClusterKey{}).(string))
+		return ctx
+	}).Feature()
+
+	code := multi.run(func() int {
+		multi.TestOnAll(t, f)
+		return 0
+	})
+	if code != 0 {
+		t.Errorf("unexpected exit code %d", code)
+	}
+
+	expected := []string{
+		"setup primary", "setup secondary",
+		"test primary", "test secondary",
+		"finish secondary", "finish primary",
+	}
+	if strings.Join(calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// testNamespaceKey is the context key of the namespace of a test, by test name
+type testNamespaceKey string
+
+const (
+	// defaultTestNamespacePrefix is the prefix of the test namespaces
+	// when WithRandomTestNamespace is called with an empty prefix
+	defaultTestNamespacePrefix = "test"
+	// maxTestNamespacePrefix is the length of the longest prefix that,
+	// followed by the random suffix, fits in a 63 characters DNS label
+	maxTestNamespacePrefix = 63 - 9
+)
+
+// WithRandomTestNamespace creates, before each Env.Test(...), a namespace
+// with a random name starting with prefix, which is deleted once the test
+// completes (see BeforeEachTestWithCleanup). The namespace of a test is
+// retrieved with TestNamespaceFromContext.
+//
+// An empty prefix defaults to "test" and a prefix is truncated to 54
+// characters, so that the name is a valid DNS label.
+func (e *testEnv) WithRandomTestNamespace(prefix string) types.Environment {
+	return e.BeforeEachTestWithCleanup(func(ctx context.Context, cfg *envconf.Config, t *testing.T) (context.Context, func(), error) {
+		client, err := cfg.Client()
+		if err != nil {
+			return ctx, nil, fmt.Errorf("create test namespace: %w", err)
+		}
+
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: randomTestNamespaceName(prefix)}}
+		if err := client.Resources().Create(ctx, namespace); err != nil {
+			return ctx, nil, fmt.Errorf("create test namespace: %w", err)
+		}
+
+		cleanup := func() {
+			if err := client.Resources().Delete(ctx, namespace); err != nil {
+				t.Errorf("delete test namespace %s: %s", namespace.Name, err)
+			}
+		}
+		return context.WithValue(ctx, testNamespaceKey(t.Name()), namespace.Name), cleanup, nil
+	})
+}
+
+// randomTestNamespaceName returns a random namespace name starting with
+// prefix, defaulted and truncated as described by WithRandomTestNamespace
+func randomTestNamespaceName(prefix string) string {
+	if prefix == "" {
+		prefix = defaultTestNamespacePrefix
+	}
+	if len(prefix) > maxTestNamespacePrefix {
+		prefix = prefix[:maxTestNamespacePrefix]
+	}
+	return envconf.RandomName(prefix, len(prefix)+9)
+}
+
+// TestNamespaceFromContext returns the namespace created for the test t, or
+// one of its parent tests, by WithRandomTestNamespace. It returns an empty
+// string when no namespace was created, i.e. when called from another test.
+func TestNamespaceFromContext(ctx context.Context, t *testing.T) string {
+	name := t.Name()
+	for {
+		if namespace, ok := ctx.Value(testNamespaceKey(name)).(string); ok {
+			return namespace
+		}
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			return ""
+		}
+		name = name[:i]
+	}
+}
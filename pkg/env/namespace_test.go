@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestEnv_WithRandomTestNamespace(t *testing.T) {
+	client := klient.NewFakeClient()
+	env := newTestEnv()
+	env.cfg = envconf.New().WithFakeClient(client)
+	env.WithRandomTestNamespace("e2e")
+
+	var namespaces []string
+	recordNamespace := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		namespace := TestNamespaceFromContext(ctx, t)
+		if err := client.Resources().Get(ctx, namespace, "", &corev1.Namespace{}); err != nil {
+			t.Errorf("get test namespace %q: %s", namespace, err)
+		}
+		namespaces = append(namespaces, namespace)
+		return ctx
+	}
+
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			env.Test(t, features.New("namespace").Assess("exists", recordNamespace).Feature())
+		})
+	}
+
+	if len(namespaces) != 2 || namespaces[0] == namespaces[1] {
+		t.Fatalf("expected a different namespace per test, got %v", namespaces)
+	}
+	for _, namespace := range namespaces {
+		if !strings.HasPrefix(namespace, "e2e-") {
+			t.Errorf("namespace %q does not start with the prefix", namespace)
+		}
+		err := client.Resources().Get(context.TODO(), namespace, "", &corev1.Namespace{})
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("namespace %q not deleted after the test: %v", namespace, err)
+		}
+	}
+}
+
+func TestEnv_RandomTestNamespaceName(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		expect string
+	}{
+		{name: "prefix", prefix: "e2e", expect: "e2e-"},
+		{name: "empty prefix", prefix: "", expect: "test-"},
+		{name: "long prefix", prefix: strings.Repeat("a", 70), expect: strings.Repeat("a", 54) + "-"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := randomTestNamespaceName(test.prefix)
+			if !strings.HasPrefix(name, test.expect) {
+				t.Errorf("namespace %q does not start with %q", name, test.expect)
+			}
+			if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+				t.Errorf("invalid namespace %q: %v", name, errs)
+			}
+		})
+	}
+}
+
+func TestTestNamespaceFromContext(t *testing.T) {
+	if namespace := TestNamespaceFromContext(context.TODO(), t); namespace != "" {
+		t.Errorf("expected no namespace, got %q", namespace)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import "errors"
+
+// ErrUnsupported is returned, wrapped, by the operations that a cluster does
+// not support, such as Pause. It is equivalent to errors.ErrUnsupported, which
+// requires Go 1.21, and can be checked with errors.Is.
+var ErrUnsupported = errors.New("unsupported")
+
+// Pauser is implemented by the clusters that can be paused, i.e. to
+// simulate an unavailable cluster during a test, like kind.Cluster.
+//
+// Clusters that cannot be paused implement Pauser with methods that return
+// ErrUnsupported, which callers can check with errors.Is, so that
+// the capability can be asserted on any cluster type.
+type Pauser interface {
+	// Pause makes the cluster unavailable until Resume is called.
+	Pause() error
+	// Resume makes a cluster paused with Pause available again.
+	Resume() error
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env_test
+
+import (
+	"errors"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/support/envtest"
+	"sigs.k8s.io/e2e-framework/support/k3d"
+	"sigs.k8s.io/e2e-framework/support/kind"
+	"sigs.k8s.io/e2e-framework/support/minikube"
+)
+
+var _ env.Pauser = (*kind.Cluster)(nil)
+
+func TestPauser_Unsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster interface{}
+	}{
+		{name: "k3d", cluster: k3d.NewCluster("test")},
+		{name: "minikube", cluster: minikube.NewCluster("test")},
+		{name: "envtest", cluster: envtest.NewCluster("test")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pauser, ok := test.cluster.(env.Pauser)
+			if !ok {
+				t.Fatalf("%T does not implement Pauser", test.cluster)
+			}
+			if err := pauser.Pause(); !errors.Is(err, env.ErrUnsupported) {
+				t.Errorf("expected Pause to return env.ErrUnsupported, got %v", err)
+			}
+			if err := pauser.Resume(); !errors.Is(err, env.ErrUnsupported) {
+				t.Errorf("expected Resume to return env.ErrUnsupported, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// skipSuiteKey is the context key of the reason, set by a setup
+// action, for skipping the remaining setup actions and the tests
+type skipSuiteKey struct{}
+
+// WithK8SVersionConstraint registers a setup action that skips the test suite
+// when the version of the cluster API server does not satisfy constraint, i.e.
+// ">=1.24, <1.29". Comparators separated by commas or spaces must all be
+// satisfied and `||` separates alternatives. Partial versions are wildcards:
+// "<1.29" excludes all 1.29 patch versions and ">1.24" requires 1.25 or newer.
+//
+// The action must be registered after the setup actions that create the
+// cluster. When the suite is skipped, the remaining setup actions and the
+// tests are not executed, the Finish operations are, and Run returns a zero
+// exit code. An invalid constraint is a fatal error.
+func (e *testEnv) WithK8SVersionConstraint(constraint string) types.Environment {
+	versionRange, err := parseVersionConstraint(constraint)
+	if err != nil {
+		log.Fatalf("kubernetes version constraint: %s", err)
+	}
+
+	return e.Setup(func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.Client()
+		if err != nil {
+			return ctx, fmt.Errorf("kubernetes version constraint: %w", err)
+		}
+		info, err := client.Resources().GetAPIServerVersion()
+		if err != nil {
+			return ctx, fmt.Errorf("kubernetes version constraint: %w", err)
+		}
+		version, err := semver.ParseTolerant(info.GitVersion)
+		if err != nil {
+			return ctx, fmt.Errorf("kubernetes version constraint: %w", err)
+		}
+		// provider suffixes, i.e. v1.27.3-eks-a5565ad, are not pre-releases
+		version.Pre, version.Build = nil, nil
+
+		if !versionRange(version) {
+			reason := fmt.Sprintf("kubernetes version %s does not satisfy %q", info.GitVersion, constraint)
+			return context.WithValue(ctx, skipSuiteKey{}, reason), nil
+		}
+		return ctx, nil
+	})
+}
+
+// parseVersionConstraint converts constraint to a semver range, replacing
+// commas with spaces (logical AND) and completing partial versions with
+// a wildcard.
+func parseVersionConstraint(constraint string) (semver.Range, error) {
+	fields := strings.Fields(strings.ReplaceAll(constraint, ",", " "))
+	for i, field := range fields {
+		version := strings.TrimLeft(field, "<>=!~^")
+		if version == "" || field == "||" {
+			continue
+		}
+		op := strings.TrimSuffix(field, version)
+		version = strings.TrimPrefix(version, "v")
+		if strings.Count(version, ".") < 2 && !strings.ContainsAny(version, "xX*") {
+			version += ".x"
+		}
+		fields[i] = op + version
+	}
+
+	versionRange, err := semver.ParseRange(strings.Join(fields, " "))
+	if err != nil {
+		return nil, fmt.Errorf("parse %q: %w", constraint, err)
+	}
+	return versionRange, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+func TestParseVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		versions   map[string]bool
+	}{
+		{
+			constraint: ">=1.24, <1.29",
+			versions:   map[string]bool{"1.23.17": false, "1.24.0": true, "1.28.9": true, "1.29.0": false},
+		},
+		{
+			constraint: ">1.24 <=1.28",
+			versions:   map[string]bool{"1.24.15": false, "1.25.0": true, "1.28.9": true, "1.29.0": false},
+		},
+		{
+			constraint: "1.21 || >=v1.27.2",
+			versions:   map[string]bool{"1.21.1": true, "1.22.0": false, "1.27.1": false, "1.27.2": true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.constraint, func(t *testing.T) {
+			versionRange, err := parseVersionConstraint(test.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for v, expected := range test.versions {
+				if versionRange(semver.MustParse(v)) != expected {
+					t.Errorf("expected %s in range to be %t", v, expected)
+				}
+			}
+		})
+	}
+
+	if _, err := parseVersionConstraint(">=one"); err == nil {
+		t.Error("expected an error for an invalid constraint")
+	}
+}
+
+func TestEnv_WithK8SVersionConstraint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(&version.Info{Major: "1", Minor: "27", GitVersion: "v1.27.3-eks-a5565ad"}); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := klient.New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		constraint string
+		skipped    bool
+	}{
+		{name: "satisfied", constraint: ">=1.24, <1.29"},
+		{name: "not satisfied", constraint: ">=1.28", skipped: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var setupRan, testsRan, finishRan bool
+			env := NewWithConfig(envconf.New().WithClient(client)).
+				WithK8SVersionConstraint(test.constraint).
+				Setup(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+					setupRan = true
+					return ctx, nil
+				}).
+				Finish(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+					finishRan = true
+					return ctx, nil
+				})
+
+			code := env.(*testEnv).run(func() int {
+				testsRan = true
+				return 1
+			})
+
+			if test.skipped {
+				if code != 0 || setupRan || testsRan {
+					t.Errorf("expected the suite to be skipped: exit code %d, setup ran %t, tests ran %t", code, setupRan, testsRan)
+				}
+			} else if code != 1 || !setupRan || !testsRan {
+				t.Errorf("expected the suite to run: exit code %d, setup ran %t, tests ran %t", code, setupRan, testsRan)
+			}
+			if !finishRan {
+				t.Error("expected the finish actions to run")
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package envconf
 import (
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"math/rand"
 	"regexp"
@@ -26,6 +27,8 @@ import (
 
 	"sigs.k8s.io/e2e-framework/klient"
 	"sigs.k8s.io/e2e-framework/pkg/flags"
+	"sigs.k8s.io/e2e-framework/pkg/report"
+	"sigs.k8s.io/yaml"
 )
 
 // Config represents and environment configuration
@@ -36,6 +39,10 @@ type Config struct {
 	assessmentRegex *regexp.Regexp
 	featureRegex    *regexp.Regexp
 	labels          map[string]string
+	failFast        bool
+	parallelism     int
+	timeout         time.Duration
+	reporter        report.Reporter
 }
 
 // New creates and initializes an empty environment configuration
@@ -56,9 +63,105 @@ func NewFromFlags() (*Config, error) {
 	e.labels = envFlags.Labels()
 	e.namespace = envFlags.Namespace()
 	e.kubeconfig = envFlags.Kubeconfig()
+	e.failFast = envFlags.FailFast()
 	return e, nil
 }
 
+// configFile is the content of a configuration file
+// (see NewFromConfigFile)
+type configFile struct {
+	Kubeconfig      string            `json:"kubeconfig"`
+	Namespace       string            `json:"namespace"`
+	FeatureRegex    string            `json:"featureRegex"`
+	AssessmentRegex string            `json:"assessmentRegex"`
+	Labels          map[string]string `json:"labels"`
+	Timeout         string            `json:"timeout"`
+}
+
+// NewFromConfigFile initializes an environment config from a YAML or JSON
+// file with the fields kubeconfig, namespace, featureRegex, assessmentRegex,
+// labels and timeout (a duration such as 30m). An error is returned when the
+// file contains unknown fields or invalid values.
+func NewFromConfigFile(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+
+	var file configFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	c := New()
+	c.kubeconfig = file.Kubeconfig
+	c.namespace = file.Namespace
+	c.labels = file.Labels
+	if file.FeatureRegex != "" {
+		if c.featureRegex, err = regexp.Compile(file.FeatureRegex); err != nil {
+			return nil, fmt.Errorf("config file %s: featureRegex: %w", path, err)
+		}
+	}
+	if file.AssessmentRegex != "" {
+		if c.assessmentRegex, err = regexp.Compile(file.AssessmentRegex); err != nil {
+			return nil, fmt.Errorf("config file %s: assessmentRegex: %w", path, err)
+		}
+	}
+	if file.Timeout != "" {
+		if c.timeout, err = time.ParseDuration(file.Timeout); err != nil {
+			return nil, fmt.Errorf("config file %s: timeout: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+// NewFromConfigFileAndFlags initializes an environment config from a
+// configuration file (see NewFromConfigFile) then overrides its values
+// with the values of the flags parsed from command-line arguments.
+func NewFromConfigFileAndFlags(path string) (*Config, error) {
+	c, err := NewFromConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	envFlags, err := flags.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return c.withFlags(envFlags)
+}
+
+// withFlags overrides the config values with the values of the flags that
+// are set. Labels set by flags are merged into the config labels.
+func (c *Config) withFlags(envFlags *flags.EnvFlags) (*Config, error) {
+	var err error
+	if envFlags.Kubeconfig() != "" {
+		c.kubeconfig = envFlags.Kubeconfig()
+	}
+	if envFlags.Namespace() != "" {
+		c.namespace = envFlags.Namespace()
+	}
+	if envFlags.Feature() != "" {
+		if c.featureRegex, err = regexp.Compile(envFlags.Feature()); err != nil {
+			return nil, fmt.Errorf("feature flag: %w", err)
+		}
+	}
+	if envFlags.Assessment() != "" {
+		if c.assessmentRegex, err = regexp.Compile(envFlags.Assessment()); err != nil {
+			return nil, fmt.Errorf("assess flag: %w", err)
+		}
+	}
+	if len(envFlags.Labels()) > 0 && c.labels == nil {
+		c.labels = make(map[string]string)
+	}
+	for key, val := range envFlags.Labels() {
+		c.labels[key] = val
+	}
+	if envFlags.FailFast() {
+		c.failFast = true
+	}
+	return c, nil
+}
+
 // WithKubeconfigFile creates a new klient.Client and injects it in the cfg
 func (c *Config) WithKubeconfigFile(kubecfg string) *Config {
 	c.kubeconfig = kubecfg
@@ -75,6 +178,13 @@ func (c *Config) WithClient(client klient.Client) *Config {
 	return c
 }
 
+// WithFakeClient sets a client, i.e. created with klient.NewFakeClient, that
+// is returned by Client instead of a client for the kubeconfig file. This
+// allows environment and feature functions to be unit tested without a cluster.
+func (c *Config) WithFakeClient(client klient.Client) *Config {
+	return c.WithClient(client)
+}
+
 // Client is a constructor function that returns a previously
 // created klient.Client or create a new one based on configuration
 // previously set
@@ -146,6 +256,78 @@ func (c *Config) Labels() map[string]string {
 	return c.labels
 }
 
+// WithFailFast causes the environment to skip the remaining
+// features, and tests, after a feature fails
+func (c *Config) WithFailFast() *Config {
+	c.failFast = true
+	return c
+}
+
+// FailFast returns true if the environment should stop
+// testing after the first feature failure
+func (c *Config) FailFast() bool {
+	return c.failFast
+}
+
+// WithParallelism sets the maximum number of features
+// tested concurrently by env.TestInParallel
+func (c *Config) WithParallelism(n int) *Config {
+	c.parallelism = n
+	return c
+}
+
+// Parallelism returns the maximum number of features tested
+// concurrently, a value of 0 or less means no limit
+func (c *Config) Parallelism() int {
+	return c.parallelism
+}
+
+// WithTimeout sets a deadline for the whole test suite run by env.Run,
+// from the setup actions to the end of the tests. When it expires, the
+// finish actions are executed and the suite fails.
+func (c *Config) WithTimeout(d time.Duration) *Config {
+	c.timeout = d
+	return c
+}
+
+// Timeout returns the deadline of the test suite, a value
+// of 0 or less means no deadline
+func (c *Config) Timeout() time.Duration {
+	return c.timeout
+}
+
+// WithReporter sets the reporter that receives the results of
+// the features and assessments tested by the environment
+func (c *Config) WithReporter(r report.Reporter) *Config {
+	c.reporter = r
+	return c
+}
+
+// Reporter returns the reporter of the test results, if any
+func (c *Config) Reporter() report.Reporter {
+	return c.reporter
+}
+
+// DeepCopy returns a copy of the configuration that shares no mutable state
+// with c: the labels are duplicated and the regex filters are compiled again
+// from the same patterns. The client and reporter, if any, are shared.
+func (c *Config) DeepCopy() *Config {
+	cp := *c
+	if c.labels != nil {
+		cp.labels = make(map[string]string, len(c.labels))
+		for key, val := range c.labels {
+			cp.labels[key] = val
+		}
+	}
+	if c.assessmentRegex != nil {
+		cp.assessmentRegex = regexp.MustCompile(c.assessmentRegex.String())
+	}
+	if c.featureRegex != nil {
+		cp.featureRegex = regexp.MustCompile(c.featureRegex.String())
+	}
+	return &cp
+}
+
 func randNS() string {
 	return RandomName("testns-", 32)
 }
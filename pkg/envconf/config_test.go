@@ -17,7 +17,16 @@ limitations under the License.
 package envconf
 
 import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/pkg/flags"
 )
 
 func TestConfig_New(t *testing.T) {
@@ -32,3 +41,136 @@ func TestConfig_New(t *testing.T) {
 		t.Errorf("regex filters should be nil")
 	}
 }
+
+func TestConfig_DeepCopy(t *testing.T) {
+	cfg := New().WithNamespace("test-ns").WithFeatureRegex("feat-.*").WithLabels(map[string]string{"env": "test"})
+	cp := cfg.DeepCopy()
+
+	if cp.Namespace() != "test-ns" || cp.FeatureRegex().String() != "feat-.*" || cp.Labels()["env"] != "test" {
+		t.Errorf("copy does not match the original config")
+	}
+	if cp.FeatureRegex() == cfg.FeatureRegex() {
+		t.Errorf("feature regex should be compiled again")
+	}
+
+	cp.Labels()["env"] = "prod"
+	cp.WithNamespace("other-ns")
+	if cfg.Labels()["env"] != "test" || cfg.Namespace() != "test-ns" {
+		t.Errorf("changes to the copy should not affect the original config")
+	}
+}
+
+func TestNewFromConfigFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		content    string
+		shouldFail bool
+	}{
+		{
+			name: "yaml",
+			file: "e2e-config.yaml",
+			content: `kubeconfig: /tmp/kubeconfig
+namespace: e2e
+featureRegex: feat-.*
+assessmentRegex: assess-.*
+labels:
+  env: ci
+timeout: 30m
+`,
+		},
+		{
+			name:    "json",
+			file:    "e2e-config.json",
+			content: `{"kubeconfig": "/tmp/kubeconfig", "namespace": "e2e", "featureRegex": "feat-.*", "assessmentRegex": "assess-.*", "labels": {"env": "ci"}, "timeout": "30m"}`,
+		},
+		{name: "unknown field", file: "e2e-config.yaml", content: "namespace: e2e\nparallel: true\n", shouldFail: true},
+		{name: "invalid timeout", file: "e2e-config.yaml", content: "timeout: soon\n", shouldFail: true},
+		{name: "invalid regex", file: "e2e-config.yaml", content: "featureRegex: \"(\"\n", shouldFail: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := NewFromConfigFile(path)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.KubeconfigFile() != "/tmp/kubeconfig" || cfg.Namespace() != "e2e" || cfg.Labels()["env"] != "ci" {
+				t.Errorf("unexpected config %+v", cfg)
+			}
+			if cfg.FeatureRegex().String() != "feat-.*" || cfg.AssessmentRegex().String() != "assess-.*" {
+				t.Errorf("unexpected regex filters %s, %s", cfg.FeatureRegex(), cfg.AssessmentRegex())
+			}
+			if cfg.Timeout() != 30*time.Minute {
+				t.Errorf("unexpected timeout %s", cfg.Timeout())
+			}
+		})
+	}
+}
+
+func TestConfig_WithFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "e2e-config.yaml")
+	content := "namespace: e2e\nfeatureRegex: feat-.*\nlabels:\n  env: ci\n  team: core\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := NewFromConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envFlags, err := flags.ParseArgs([]string{"-namespace", "override", "-labels", "env=prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = cfg.withFlags(envFlags); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Namespace() != "override" {
+		t.Errorf("expected the namespace flag to take precedence, got %s", cfg.Namespace())
+	}
+	if cfg.FeatureRegex().String() != "feat-.*" {
+		t.Errorf("expected the feature regex of the file to be kept, got %s", cfg.FeatureRegex())
+	}
+	if cfg.Labels()["env"] != "prod" || cfg.Labels()["team"] != "core" {
+		t.Errorf("expected the flag labels to be merged into the file labels, got %v", cfg.Labels())
+	}
+}
+
+func TestConfig_WithFakeClient(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "setup-cm", Namespace: "default"}}
+	setup := func(ctx context.Context, cfg *Config) (context.Context, error) {
+		client, err := cfg.Client()
+		if err != nil {
+			return ctx, err
+		}
+		return ctx, client.Resources().Create(ctx, cm.DeepCopy())
+	}
+
+	cfg := New().WithFakeClient(klient.NewFakeClient())
+	if _, err := setup(context.TODO(), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := cfg.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created corev1.ConfigMap
+	if err := client.Resources().Get(context.TODO(), cm.Name, cm.Namespace, &created); err != nil {
+		t.Errorf("configmap created by the setup function not found: %s", err)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+
+	crenvtest "sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/support/envtest"
+)
+
+type envtestContextKey string
+
+// CreateEnvtestCluster returns an env.Func that is used to start an envtest
+// control plane (see envtest.Cluster) that is then injected in the context
+// using the name as a key. The CRDs of the files in crdPaths (directories or
+// files) are installed once the control plane is started.
+//
+// NOTE: the returned function will update its env config with the
+// kubeconfig file for the config client.
+func CreateEnvtestCluster(clusterName string, crdPaths ...string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		c := envtest.NewCluster(clusterName).WithEnvironment(&crenvtest.Environment{CRDDirectoryPaths: crdPaths})
+		kubecfg, err := c.Create()
+		if err != nil {
+			return ctx, err
+		}
+
+		// update envconfig  with kubeconfig
+		cfg.WithKubeconfigFile(kubecfg)
+		// store entire cluster value in ctx for future access using the cluster name
+		return context.WithValue(ctx, envtestContextKey(clusterName), c), nil
+	}
+}
+
+// DestroyEnvtestCluster returns an EnvFunc that retrieves a previously
+// saved envtest Cluster in the context (using the name), then stops it.
+//
+// NOTE: this should be used in a Environment.Finish step.
+func DestroyEnvtestCluster(name string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		clusterVal := ctx.Value(envtestContextKey(name))
+		if clusterVal == nil {
+			return ctx, fmt.Errorf("destroy envtest cluster func: context cluster is nil")
+		}
+
+		cluster, ok := clusterVal.(*envtest.Cluster)
+		if !ok {
+			return ctx, fmt.Errorf("destroy envtest cluster func: unexpected type for cluster value")
+		}
+
+		if err := cluster.Destroy(); err != nil {
+			return ctx, fmt.Errorf("destroy envtest cluster: %w", err)
+		}
+
+		return ctx, nil
+	}
+}
@@ -33,11 +33,22 @@ type kindContextKey string
 // using the name as a key.
 //
 // NOTE: the returned function will update its env config with the
-// kubeconfig file for the config client.
+// kubeconfig file for the config client. The cluster logs are exported
+// when the test suite fails (see env.WithLogExporter).
 //
 func CreateKindCluster(clusterName string) env.Func {
+	return createKindCluster(clusterName, kind.NewCluster(clusterName))
+}
+
+// CreateKindClusterWithConfig returns an env.Func, like CreateKindCluster,
+// that creates a kind cluster using the kind configuration configYAML,
+// i.e. to create a multi-node cluster.
+func CreateKindClusterWithConfig(clusterName, configYAML string) env.Func {
+	return createKindCluster(clusterName, kind.NewCluster(clusterName).WithConfig(configYAML))
+}
+
+func createKindCluster(clusterName string, k *kind.Cluster) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-		k := kind.NewCluster(clusterName)
 		kubecfg, err := k.Create()
 		if err != nil {
 			return ctx, err
@@ -48,6 +59,8 @@ func CreateKindCluster(clusterName string) env.Func {
 
 		// update envconfig  with kubeconfig
 		cfg.WithKubeconfigFile(kubecfg)
+		// export the cluster logs when the test suite fails
+		ctx = env.WithLogExporter(ctx, clusterName, k)
 		// store entire cluster value in ctx for future access using the cluster name
 		return context.WithValue(ctx, kindContextKey(clusterName), k), nil
 	}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/support/minikube"
+)
+
+type minikubeContextKey string
+
+// CreateMinikubeCluster returns an env.Func that is used to
+// create a minikube cluster, using the name as its profile, that
+// is then injected in the context using the name as a key.
+//
+// NOTE: the returned function will update its env config with the
+// kubeconfig file for the config client.
+func CreateMinikubeCluster(clusterName string) env.Func {
+	return createMinikubeCluster(clusterName, minikube.NewCluster(clusterName))
+}
+
+// CreateMinikubeClusterWithOptions returns an env.Func, like
+// CreateMinikubeCluster, that creates a minikube cluster using
+// the driver, CPUs and memory set in opts.
+func CreateMinikubeClusterWithOptions(clusterName string, opts minikube.CreateOptions) env.Func {
+	return createMinikubeCluster(clusterName, minikube.NewCluster(clusterName).WithCreateOptions(opts))
+}
+
+func createMinikubeCluster(clusterName string, m *minikube.Cluster) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		kubecfg, err := m.Create()
+		if err != nil {
+			return ctx, err
+		}
+
+		// update envconfig  with kubeconfig
+		cfg.WithKubeconfigFile(kubecfg)
+		// store entire cluster value in ctx for future access using the cluster name
+		return context.WithValue(ctx, minikubeContextKey(clusterName), m), nil
+	}
+}
+
+// DestroyMinikubeCluster returns an EnvFunc that
+// retrieves a previously saved minikube Cluster in the context (using the name), then deletes it.
+//
+// NOTE: this should be used in a Environment.Finish step.
+func DestroyMinikubeCluster(name string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		clusterVal := ctx.Value(minikubeContextKey(name))
+		if clusterVal == nil {
+			return ctx, fmt.Errorf("destroy minikube cluster func: context cluster is nil")
+		}
+
+		cluster, ok := clusterVal.(*minikube.Cluster)
+		if !ok {
+			return ctx, fmt.Errorf("destroy minikube cluster func: unexpected type for cluster value")
+		}
+
+		if err := cluster.Destroy(); err != nil {
+			return ctx, fmt.Errorf("destroy minikube cluster: %w", err)
+		}
+
+		return ctx, nil
+	}
+}
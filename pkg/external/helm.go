@@ -0,0 +1,417 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package external provides helpers to drive external tools,
+// such as helm, from within tests.
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+type helmOpts struct {
+	name      string
+	namespace string
+	chart     string
+	version   string
+	wait      bool
+	reuse     bool
+	timeout   time.Duration
+	args      []string
+
+	kubeAPIServer string
+	kubeToken     string
+	kubeCACert    string
+
+	registryLogin  *registryCredentials
+	registryLogout string
+}
+
+// registryCredentials are the credentials of a chart registry.
+type registryCredentials struct {
+	registry string
+	username string
+	password string
+}
+
+// HelmOption is used to provide the arguments of a helm command.
+type HelmOption func(*helmOpts)
+
+// WithName sets the name of the helm release.
+func WithName(name string) HelmOption {
+	return func(o *helmOpts) { o.name = name }
+}
+
+// WithNamespace sets the namespace of the helm release.
+func WithNamespace(namespace string) HelmOption {
+	return func(o *helmOpts) { o.namespace = namespace }
+}
+
+// WithChart sets the chart (reference, path or URL) to install.
+func WithChart(chart string) HelmOption {
+	return func(o *helmOpts) { o.chart = chart }
+}
+
+// WithVersion sets the version of the chart to install.
+func WithVersion(version string) HelmOption {
+	return func(o *helmOpts) { o.version = version }
+}
+
+// WithWait makes helm wait until the release resources are ready.
+func WithWait() HelmOption {
+	return func(o *helmOpts) { o.wait = true }
+}
+
+// WithReuse makes helm upgrade reuse the values of the last release,
+// merged with the values set by the upgrade command (--reuse-values).
+func WithReuse(reuse bool) HelmOption {
+	return func(o *helmOpts) { o.reuse = reuse }
+}
+
+// WithTimeout sets the time helm waits for individual kubernetes operations.
+func WithTimeout(timeout time.Duration) HelmOption {
+	return func(o *helmOpts) { o.timeout = timeout }
+}
+
+// WithArgs appends arbitrary arguments to the helm command.
+func WithArgs(args ...string) HelmOption {
+	return func(o *helmOpts) { o.args = append(o.args, args...) }
+}
+
+// WithValuesFile sets a values file (--values) of the release. It can
+// be set several times, the last file taking precedence.
+func WithValuesFile(path string) HelmOption {
+	return WithArgs("--values", path)
+}
+
+// WithSetValue sets a value (--set) of the release, i.e.
+// WithSetValue("image.tag", "1.21.1").
+func WithSetValue(key, value string) HelmOption {
+	return WithArgs("--set", fmt.Sprintf("%s=%s", key, value))
+}
+
+// WithSetStringValue sets a value (--set-string) of the release,
+// which is kept as a string, i.e. WithSetStringValue("version", "1.10").
+func WithSetStringValue(key, value string) HelmOption {
+	return WithArgs("--set-string", fmt.Sprintf("%s=%s", key, value))
+}
+
+// WithKubeAPIServer sets the address of the API server helm connects to.
+// When set, it replaces the kubeconfig of the HelmManager, which allows
+// running helm from within a pod along with WithKubeToken and WithKubeCACert.
+func WithKubeAPIServer(addr string) HelmOption {
+	return func(o *helmOpts) { o.kubeAPIServer = addr }
+}
+
+// WithKubeToken sets the bearer token used to authenticate
+// with the API server set by WithKubeAPIServer.
+func WithKubeToken(token string) HelmOption {
+	return func(o *helmOpts) { o.kubeToken = token }
+}
+
+// WithKubeCACert sets the path of the certificate authority file used to
+// verify the API server set by WithKubeAPIServer.
+func WithKubeCACert(certPath string) HelmOption {
+	return func(o *helmOpts) { o.kubeCACert = certPath }
+}
+
+// WithRegistryLogin logs in to the chart registry, using `helm registry login`,
+// before running the helm command. The HelmManager keeps track of the registry
+// so Cleanup logs out from it.
+func WithRegistryLogin(registry, username, password string) HelmOption {
+	return func(o *helmOpts) {
+		o.registryLogin = &registryCredentials{registry: registry, username: username, password: password}
+	}
+}
+
+// WithRegistryLogout logs out from the chart registry, using
+// `helm registry logout`, after running the helm command.
+func WithRegistryLogout(registry string) HelmOption {
+	return func(o *helmOpts) { o.registryLogout = registry }
+}
+
+// HelmManager runs helm commands against the cluster of a kubeconfig file.
+type HelmManager struct {
+	kubeConfig string
+	path       string
+	// registries are the chart registries logged in to, by registry
+	registries map[string]registryCredentials
+}
+
+// NewHelmManager returns a HelmManager that uses the kubeconfig file.
+func NewHelmManager(kubeConfig string) *HelmManager {
+	return &HelmManager{kubeConfig: kubeConfig, path: "helm", registries: make(map[string]registryCredentials)}
+}
+
+// WithPath sets the path of the helm binary, defaults to helm from $PATH.
+func (m *HelmManager) WithPath(path string) *HelmManager {
+	m.path = path
+	return m
+}
+
+// RunInstall runs `helm install` for the chart and release name set in opts.
+func (m *HelmManager) RunInstall(opts ...HelmOption) error {
+	return m.run("install", opts)
+}
+
+// RunUpgrade runs `helm upgrade` for the chart and release name set in opts.
+func (m *HelmManager) RunUpgrade(opts ...HelmOption) error {
+	return m.run("upgrade", opts)
+}
+
+// RunUninstall runs `helm uninstall` for the release name set in opts.
+func (m *HelmManager) RunUninstall(opts ...HelmOption) error {
+	return m.run("uninstall", opts)
+}
+
+// RunTemplate runs `helm template` for the chart and release name set in
+// opts and returns the rendered manifests, without installing anything.
+func (m *HelmManager) RunTemplate(opts ...HelmOption) ([]byte, error) {
+	return m.runCommand("template", opts, func(args []string) ([]byte, error) {
+		// only stdout holds the manifests, helm writes warnings and errors to stderr
+		var stdout, stderr bytes.Buffer
+		command := exec.Command(args[0], args[1:]...)
+		command.Stdout, command.Stderr = &stdout, &stderr
+		if err := command.Run(); err != nil {
+			return nil, fmt.Errorf("helm template: %s: %w", strings.TrimSpace(stderr.String()), err)
+		}
+		return stdout.Bytes(), nil
+	})
+}
+
+// RenderAndDecode renders the chart, like RunTemplate, and decodes the
+// manifests into objects of the types registered in scheme, defaulting to
+// the client-go scheme. Manifests of other types are decoded into
+// *unstructured.Unstructured values.
+func (m *HelmManager) RenderAndDecode(scheme *runtime.Scheme, opts ...HelmOption) ([]runtime.Object, error) {
+	manifests, err := m.RunTemplate(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return decodeManifests(manifests, scheme)
+}
+
+// RunRepo runs `helm repo`, using the arguments set with WithArgs
+// (i.e. WithArgs("add", "bitnami", "https://charts.bitnami.com/bitnami")).
+func (m *HelmManager) RunRepo(opts ...HelmOption) error {
+	return m.run("repo", opts)
+}
+
+// RunTest runs `helm test` for the release name set in opts.
+func (m *HelmManager) RunTest(opts ...HelmOption) error {
+	return m.run("test", opts)
+}
+
+func (m *HelmManager) run(operation string, opts []HelmOption) error {
+	_, err := m.runCommand(operation, opts, func(args []string) ([]byte, error) {
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("helm %s: %s: %w", operation, strings.TrimSpace(string(out)), err)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// runCommand runs the helm command arguments of the operation with runArgs,
+// logging in to and out from the chart registries set in opts.
+func (m *HelmManager) runCommand(operation string, opts []HelmOption, runArgs func(args []string) ([]byte, error)) ([]byte, error) {
+	o := &helmOpts{}
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	args, err := m.getCommand(operation, o)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.registryLogin != nil {
+		if err := m.registryLogin(*o.registryLogin); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("Running helm %s %s", operation, o.name)
+	out, err := runArgs(args)
+
+	if o.registryLogout != "" {
+		if logoutErr := m.registryLogout(o.registryLogout); logoutErr != nil && err == nil {
+			err = logoutErr
+		}
+	}
+	return out, err
+}
+
+// Cleanup logs out from the chart registries logged in to with
+// WithRegistryLogin and not logged out from yet.
+func (m *HelmManager) Cleanup() error {
+	var errs []string
+	for registry := range m.registries {
+		if err := m.registryLogout(registry); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("helm cleanup: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (m *HelmManager) registryLogin(creds registryCredentials) error {
+	log.Printf("Logging in to helm registry %s", creds.registry)
+	if out, err := m.getRegistryLoginCommand(creds).CombinedOutput(); err != nil {
+		return fmt.Errorf("helm registry login %s: %s: %w", creds.registry, out, err)
+	}
+	m.registries[creds.registry] = creds
+	return nil
+}
+
+func (m *HelmManager) registryLogout(registry string) error {
+	log.Printf("Logging out from helm registry %s", registry)
+	if out, err := m.getRegistryLogoutCommand(registry).CombinedOutput(); err != nil {
+		return fmt.Errorf("helm registry logout %s: %s: %w", registry, strings.TrimSpace(string(out)), err)
+	}
+	delete(m.registries, registry)
+	return nil
+}
+
+// getRegistryLoginCommand returns the helm command to log in to the registry.
+// The password is written to the standard input of the command, keeping it
+// out of the command line and away from shell expansion.
+func (m *HelmManager) getRegistryLoginCommand(creds registryCredentials) *exec.Cmd {
+	cmd := exec.Command(m.path, "registry", "login", creds.registry, "--username", creds.username, "--password-stdin")
+	cmd.Stdin = strings.NewReader(creds.password)
+	return cmd
+}
+
+// getRegistryLogoutCommand returns the helm command to log out from the registry.
+func (m *HelmManager) getRegistryLogoutCommand(registry string) *exec.Cmd {
+	return exec.Command(m.path, "registry", "logout", registry)
+}
+
+// getCommand returns the helm command arguments, starting with the helm
+// binary, for the operation. The arguments are passed to the command as is,
+// without shell expansion or splitting, so values may contain spaces.
+func (m *HelmManager) getCommand(operation string, o *helmOpts) ([]string, error) {
+	args := []string{m.path, operation}
+	switch operation {
+	case "install", "upgrade", "template":
+		if o.name == "" || o.chart == "" {
+			return nil, fmt.Errorf("helm %s: release name and chart are required", operation)
+		}
+		args = append(args, o.name, o.chart)
+	case "test", "uninstall":
+		if o.name == "" {
+			return nil, fmt.Errorf("helm %s: release name is required", operation)
+		}
+		args = append(args, o.name)
+	}
+
+	if o.namespace != "" {
+		args = append(args, "--namespace", o.namespace)
+	}
+	if o.version != "" {
+		args = append(args, "--version", o.version)
+	}
+	if o.wait {
+		args = append(args, "--wait")
+	}
+	if o.reuse {
+		args = append(args, "--reuse-values")
+	}
+	if o.timeout > 0 {
+		args = append(args, "--timeout", o.timeout.String())
+	}
+	args = append(args, o.args...)
+	args = append(args, m.kubeArgs(o)...)
+
+	return args, nil
+}
+
+// kubeArgs returns the arguments used to connect to the cluster: the API
+// server address and credentials when set, the kubeconfig file otherwise.
+func (m *HelmManager) kubeArgs(o *helmOpts) []string {
+	if o.kubeAPIServer != "" {
+		args := []string{"--kube-apiserver", o.kubeAPIServer}
+		if o.kubeToken != "" {
+			args = append(args, "--kube-token", o.kubeToken)
+		}
+		if o.kubeCACert != "" {
+			args = append(args, "--kube-ca-file", o.kubeCACert)
+		}
+		return args
+	}
+	if m.kubeConfig != "" {
+		return []string{"--kubeconfig", m.kubeConfig}
+	}
+	return nil
+}
+
+// decodeManifests decodes the documents of the YAML manifests, skipping
+// empty documents, into objects of the types registered in scheme, or
+// *unstructured.Unstructured values for the types that are not.
+func decodeManifests(manifests []byte, scheme *runtime.Scheme) ([]runtime.Object, error) {
+	if scheme == nil {
+		scheme = clientgoscheme.Scheme
+	}
+
+	var objs []runtime.Object
+	yamlReader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifests)))
+	for {
+		doc, err := yamlReader.Read()
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read yaml document: %w", err)
+		}
+
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(doc, &u.Object); err != nil {
+			return nil, fmt.Errorf("decode yaml document: %w", err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+
+		gvk := u.GroupVersionKind()
+		if !scheme.Recognizes(gvk) {
+			objs = append(objs, u)
+			continue
+		}
+		typed, err := scheme.New(gvk)
+		if err != nil {
+			return nil, err
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+			return nil, fmt.Errorf("convert %s %s: %w", gvk.Kind, u.GetName(), err)
+		}
+		objs = append(objs, typed)
+	}
+}
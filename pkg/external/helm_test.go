@@ -0,0 +1,291 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestHelmManager_GetCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		operation  string
+		opts       []HelmOption
+		expected   string
+		shouldFail bool
+	}{
+		{
+			name:      "install with kubeconfig",
+			operation: "install",
+			opts:      []HelmOption{WithName("nginx"), WithChart("bitnami/nginx"), WithNamespace("web"), WithWait(), WithTimeout(2 * time.Minute)},
+			expected:  "helm install nginx bitnami/nginx --namespace web --wait --timeout 2m0s --kubeconfig /tmp/kubecfg",
+		},
+		{
+			name:      "install with api server",
+			operation: "install",
+			opts: []HelmOption{
+				WithName("nginx"), WithChart("bitnami/nginx"),
+				WithKubeAPIServer("https://10.96.0.1:443"), WithKubeToken("token"), WithKubeCACert("/var/run/ca.crt"),
+			},
+			expected: "helm install nginx bitnami/nginx --kube-apiserver https://10.96.0.1:443 --kube-token token --kube-ca-file /var/run/ca.crt",
+		},
+		{
+			name:       "install without chart",
+			operation:  "install",
+			opts:       []HelmOption{WithName("nginx")},
+			shouldFail: true,
+		},
+		{
+			name:      "upgrade reusing values",
+			operation: "upgrade",
+			opts: []HelmOption{
+				WithName("nginx"), WithChart("bitnami/nginx"), WithNamespace("web"), WithVersion("9.5.0"),
+				WithReuse(true), WithArgs("--set", "image.tag=1.21.1"),
+			},
+			expected: "helm upgrade nginx bitnami/nginx --namespace web --version 9.5.0 --reuse-values --set image.tag=1.21.1 --kubeconfig /tmp/kubecfg",
+		},
+		{
+			name:       "upgrade without release name",
+			operation:  "upgrade",
+			opts:       []HelmOption{WithChart("bitnami/nginx")},
+			shouldFail: true,
+		},
+		{
+			name:      "uninstall",
+			operation: "uninstall",
+			opts:      []HelmOption{WithName("nginx"), WithNamespace("web"), WithWait()},
+			expected:  "helm uninstall nginx --namespace web --wait --kubeconfig /tmp/kubecfg",
+		},
+		{
+			name:       "uninstall without release name",
+			operation:  "uninstall",
+			opts:       []HelmOption{WithNamespace("web")},
+			shouldFail: true,
+		},
+		{
+			name:      "install with values",
+			operation: "install",
+			opts: []HelmOption{
+				WithName("nginx"), WithChart("bitnami/nginx"), WithValuesFile("values.yaml"), WithValuesFile("values-ci.yaml"),
+				WithSetValue("replicaCount", "2"), WithArgs("--atomic"), WithSetStringValue("image.tag", "1.21"),
+			},
+			expected: "helm install nginx bitnami/nginx --values values.yaml --values values-ci.yaml --set replicaCount=2 --atomic --set-string image.tag=1.21 --kubeconfig /tmp/kubecfg",
+		},
+		{
+			name:      "template",
+			operation: "template",
+			opts:      []HelmOption{WithName("nginx"), WithChart("./charts/nginx"), WithNamespace("web"), WithSetValue("replicaCount", "3")},
+			expected:  "helm template nginx ./charts/nginx --namespace web --set replicaCount=3 --kubeconfig /tmp/kubecfg",
+		},
+		{
+			name:      "repo add",
+			operation: "repo",
+			opts:      []HelmOption{WithArgs("add", "bitnami", "https://charts.bitnami.com/bitnami")},
+			expected:  "helm repo add bitnami https://charts.bitnami.com/bitnami --kubeconfig /tmp/kubecfg",
+		},
+	}
+
+	m := NewHelmManager("/tmp/kubecfg")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := &helmOpts{}
+			for _, fn := range test.opts {
+				fn(o)
+			}
+			cmd, err := m.getCommand(test.operation, o)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if strings.Join(cmd, " ") != test.expected {
+				t.Errorf("expected command %q, got %q", test.expected, cmd)
+			}
+		})
+	}
+}
+
+func TestHelmManager_GetRegistryCommands(t *testing.T) {
+	m := NewHelmManager("/tmp/kubecfg").WithPath("/usr/local/bin/helm")
+
+	password := "$ecret"
+	login := m.getRegistryLoginCommand(registryCredentials{registry: "registry.example.com", username: "user", password: password})
+	expected := "/usr/local/bin/helm registry login registry.example.com --username user --password-stdin"
+	if cmd := strings.Join(login.Args, " "); cmd != expected {
+		t.Errorf("expected command %q, got %q", expected, cmd)
+	}
+	for _, arg := range login.Args {
+		if strings.Contains(arg, password) {
+			t.Errorf("password found in the command arguments %q", login.Args)
+		}
+	}
+	stdin, err := ioutil.ReadAll(login.Stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stdin) != password {
+		t.Errorf("expected the password %q on stdin, got %q", password, stdin)
+	}
+
+	logout := m.getRegistryLogoutCommand("registry.example.com")
+	expected = "/usr/local/bin/helm registry logout registry.example.com"
+	if cmd := strings.Join(logout.Args, " "); cmd != expected {
+		t.Errorf("expected command %q, got %q", expected, cmd)
+	}
+}
+
+func TestHelmManager_RunTemplate(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		expected   string
+		shouldFail bool
+	}{
+		{
+			name:     "manifests without warnings",
+			script:   "echo 'WARNING: chart is deprecated' >&2\necho 'kind: ServiceAccount'",
+			expected: "kind: ServiceAccount\n",
+		},
+		{
+			name:       "failure with stderr",
+			script:     "echo 'Error: chart \"missing\" not found' >&2\nexit 1",
+			expected:   `chart "missing" not found`,
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// a fake helm binary running the test script
+			helm := filepath.Join(t.TempDir(), "helm")
+			if err := ioutil.WriteFile(helm, []byte("#!/bin/sh\n"+test.script+"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := NewHelmManager("/tmp/kubecfg").WithPath(helm).RunTemplate(WithName("nginx"), WithChart("bitnami/nginx"))
+			if test.shouldFail {
+				if err == nil || !strings.Contains(err.Error(), test.expected) {
+					t.Errorf("expected an error containing %q, got %v", test.expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != test.expected {
+				t.Errorf("expected manifests %q, got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestHelmManager_RunWithSpaces(t *testing.T) {
+	// a fake helm binary that prints its arguments, one per line, and
+	// fails unless the value with a space is passed as a single argument
+	dir := t.TempDir()
+	helm := filepath.Join(dir, "helm")
+	script := "#!/bin/sh\nstatus=1\nfor arg in \"$@\"; do echo \"$arg\"; [ \"$arg\" = \"msg=a b\" ] && status=0; done\nexit $status\n"
+	if err := ioutil.WriteFile(helm, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := NewHelmManager(filepath.Join(dir, "kube config")).WithPath(helm)
+	opts := []HelmOption{WithName("nginx"), WithChart("./my charts/nginx"), WithSetValue("msg", "a b"), WithSetStringValue("token", "$HOME")}
+
+	out, err := m.RunTemplate(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	expected := []string{"template", "nginx", "./my charts/nginx", "--set", "msg=a b", "--set-string", "token=$HOME", "--kubeconfig", filepath.Join(dir, "kube config")}
+	if strings.Join(args, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected arguments %q, got %q", expected, args)
+	}
+
+	if err := m.RunInstall(opts...); err != nil {
+		t.Errorf("unexpected install error: %s", err)
+	}
+}
+
+func TestDecodeManifests(t *testing.T) {
+	manifests := `---
+# Source: nginx/templates/serviceaccount.yaml
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: nginx
+---
+# Source: nginx/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  labels:
+    app: nginx
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.21.1
+---
+# Source: nginx/templates/monitor.yaml
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: nginx
+`
+
+	objs, err := decodeManifests([]byte(manifests), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(objs))
+	}
+	if _, ok := objs[0].(*corev1.ServiceAccount); !ok {
+		t.Errorf("expected a service account, got %T", objs[0])
+	}
+	deployment, ok := objs[1].(*appsv1.Deployment)
+	if !ok {
+		t.Fatalf("expected a deployment, got %T", objs[1])
+	}
+	if *deployment.Spec.Replicas != 3 || deployment.Spec.Template.Spec.Containers[0].Image != "nginx:1.21.1" {
+		t.Errorf("unexpected deployment spec %+v", deployment.Spec)
+	}
+	if u, ok := objs[2].(*unstructured.Unstructured); !ok || u.GetKind() != "ServiceMonitor" {
+		t.Errorf("expected an unstructured service monitor, got %T", objs[2])
+	}
+}
@@ -0,0 +1,77 @@
+//go:build helm
+// +build helm
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/support/kind"
+)
+
+// TestHelmManager_InstallUpgrade requires the helm binary and docker,
+// to create a kind cluster.
+func TestHelmManager_InstallUpgrade(t *testing.T) {
+	cluster := kind.NewCluster("e2e-framework-helm")
+	kubecfg, err := cluster.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := cluster.Destroy(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	client, err := klient.NewWithKubeConfigFile(kubecfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewHelmManager(kubecfg)
+	opts := []HelmOption{WithName("upgrade-test"), WithNamespace("default"), WithChart("testdata/chart")}
+
+	if err := m.RunInstall(opts...); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := m.RunUninstall(WithName("upgrade-test"), WithNamespace("default")); err != nil {
+			t.Error(err)
+		}
+	}()
+	assertImage(t, client, "nginx:1.20.1")
+
+	if err := m.RunUpgrade(append(opts, WithValuesFile("testdata/upgrade-values.yaml"))...); err != nil {
+		t.Fatal(err)
+	}
+	assertImage(t, client, "nginx:1.21.1")
+}
+
+func assertImage(t *testing.T, client klient.Client, expected string) {
+	t.Helper()
+	var dep appsv1.Deployment
+	if err := client.Resources().Get(context.TODO(), "upgrade-test", "default", &dep); err != nil {
+		t.Fatal(err)
+	}
+	if image := dep.Spec.Template.Spec.Containers[0].Image; image != expected {
+		t.Errorf("expected deployment image %s, got %s", expected, image)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+// pipelineRunGVK is the kind of the Tekton PipelineRuns managed by TektonManager
+var pipelineRunGVK = schema.GroupVersionKind{Group: "tekton.dev", Version: "v1beta1", Kind: "PipelineRun"}
+
+// TektonManager creates and monitors Tekton PipelineRuns. PipelineRuns are
+// handled as unstructured objects, so Tekton is only required in the cluster.
+type TektonManager struct {
+	client klient.Client
+}
+
+// NewTektonManager returns a TektonManager that uses the client.
+func NewTektonManager(client klient.Client) *TektonManager {
+	return &TektonManager{client: client}
+}
+
+// CreatePipelineRun creates the named PipelineRun of the pipeline
+// with the parameters.
+func (m *TektonManager) CreatePipelineRun(ctx context.Context, name, namespace, pipelineName string, params map[string]string) error {
+	run := newPipelineRun(name, namespace, pipelineName, params)
+	if err := m.client.Resources().Create(ctx, run); err != nil {
+		return fmt.Errorf("create pipelinerun %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// WaitPipelineRunCompleted waits until the PipelineRun succeeds, the wait
+// times out, or ctx is done. An error is returned as soon as it fails.
+func (m *TektonManager) WaitPipelineRunCompleted(ctx context.Context, name, namespace string, opts ...wait.Option) error {
+	return wait.For(m.pipelineRunDone(ctx, name, namespace, corev1.ConditionTrue), opts...)
+}
+
+// WaitPipelineRunFailed waits until the PipelineRun fails, the wait
+// times out, or ctx is done. An error is returned as soon as it succeeds.
+func (m *TektonManager) WaitPipelineRunFailed(ctx context.Context, name, namespace string, opts ...wait.Option) error {
+	return wait.For(m.pipelineRunDone(ctx, name, namespace, corev1.ConditionFalse), opts...)
+}
+
+// GetPipelineRunLogs returns the logs of the steps of the PipelineRun's
+// TaskRuns, keyed by <taskrun name>/<step container name>. The TaskRuns are
+// looked up in the child references of the PipelineRun, or in its embedded
+// TaskRun statuses with older Tekton versions.
+func (m *TektonManager) GetPipelineRunLogs(ctx context.Context, name, namespace string) (map[string]string, error) {
+	run, err := m.getPipelineRun(ctx, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	taskRunPods, err := m.getTaskRunPods(ctx, run)
+	if err != nil {
+		return nil, fmt.Errorf("pipelinerun %s/%s: %w", namespace, name, err)
+	}
+
+	logs := make(map[string]string)
+	for taskRunName, podName := range taskRunPods {
+		var pod corev1.Pod
+		if err := m.client.Resources().Get(ctx, podName, namespace, &pod); err != nil {
+			return nil, fmt.Errorf("pipelinerun %s/%s: get pod of taskrun %s: %w", namespace, name, taskRunName, err)
+		}
+		for _, container := range pod.Spec.Containers {
+			stepLogs, err := m.client.Resources().GetLogsAsString(ctx, &pod, &corev1.PodLogOptions{Container: container.Name})
+			if err != nil {
+				return nil, fmt.Errorf("pipelinerun %s/%s: %w", namespace, name, err)
+			}
+			logs[taskRunName+"/"+container.Name] = stepLogs
+		}
+	}
+	return logs, nil
+}
+
+// getTaskRunPods returns the names of the pods of the PipelineRun's TaskRuns
+// that have one, keyed by TaskRun name. TaskRuns are read from the child
+// references of the PipelineRun, falling back to the TaskRun statuses
+// embedded in the PipelineRun status.
+func (m *TektonManager) getTaskRunPods(ctx context.Context, run *unstructured.Unstructured) (map[string]string, error) {
+	pods := make(map[string]string)
+
+	children, found, err := unstructured.NestedSlice(run.Object, "status", "childReferences")
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		for _, child := range children {
+			ref, ok := child.(map[string]interface{})
+			if !ok || ref["kind"] != "TaskRun" {
+				continue
+			}
+			taskRunName, _ := ref["name"].(string)
+			apiVersion, _ := ref["apiVersion"].(string)
+			if apiVersion == "" {
+				apiVersion = pipelineRunGVK.GroupVersion().String()
+			}
+
+			taskRun := &unstructured.Unstructured{}
+			taskRun.SetAPIVersion(apiVersion)
+			taskRun.SetKind("TaskRun")
+			if err := m.client.Resources().Get(ctx, taskRunName, run.GetNamespace(), taskRun); err != nil {
+				return nil, fmt.Errorf("get taskrun %s: %w", taskRunName, err)
+			}
+			if podName, _, _ := unstructured.NestedString(taskRun.Object, "status", "podName"); podName != "" {
+				pods[taskRunName] = podName
+			}
+		}
+		return pods, nil
+	}
+
+	taskRuns, _, err := unstructured.NestedMap(run.Object, "status", "taskRuns")
+	if err != nil {
+		return nil, err
+	}
+	for taskRunName := range taskRuns {
+		if podName, _, _ := unstructured.NestedString(taskRuns, taskRunName, "status", "podName"); podName != "" {
+			pods[taskRunName] = podName
+		}
+	}
+	return pods, nil
+}
+
+// pipelineRunDone returns a condition function that returns true when
+// the status of the PipelineRun's Succeeded condition is expected, and
+// an error when the PipelineRun completed with the other status.
+func (m *TektonManager) pipelineRunDone(ctx context.Context, name, namespace string, expected corev1.ConditionStatus) apimachinerywait.ConditionFunc {
+	return func() (done bool, err error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		run, err := m.getPipelineRun(ctx, name, namespace)
+		if err != nil {
+			return false, err
+		}
+
+		status, reason, message := succeededCondition(run)
+		switch {
+		case status == corev1.ConditionUnknown:
+			return false, nil
+		case status != expected:
+			return false, fmt.Errorf("pipelinerun %s/%s completed with status %s: %s: %s", namespace, name, status, reason, message)
+		}
+		return true, nil
+	}
+}
+
+func (m *TektonManager) getPipelineRun(ctx context.Context, name, namespace string) (*unstructured.Unstructured, error) {
+	run := &unstructured.Unstructured{}
+	run.SetGroupVersionKind(pipelineRunGVK)
+	if err := m.client.Resources().Get(ctx, name, namespace, run); err != nil {
+		return nil, fmt.Errorf("get pipelinerun %s/%s: %w", namespace, name, err)
+	}
+	return run, nil
+}
+
+// newPipelineRun returns the PipelineRun of the pipeline, with the
+// parameters sorted by name.
+func newPipelineRun(name, namespace, pipelineName string, params map[string]string) *unstructured.Unstructured {
+	names := make([]string, 0, len(params))
+	for param := range params {
+		names = append(names, param)
+	}
+	sort.Strings(names)
+
+	runParams := make([]interface{}, 0, len(params))
+	for _, param := range names {
+		runParams = append(runParams, map[string]interface{}{"name": param, "value": params[param]})
+	}
+
+	run := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"pipelineRef": map[string]interface{}{"name": pipelineName},
+			"params":      runParams,
+		},
+	}}
+	run.SetGroupVersionKind(pipelineRunGVK)
+	run.SetName(name)
+	run.SetNamespace(namespace)
+	return run
+}
+
+// succeededCondition returns the status, reason and message of the Succeeded
+// condition of the PipelineRun, the status is unknown while it is not set.
+func succeededCondition(run *unstructured.Unstructured) (status corev1.ConditionStatus, reason, message string) {
+	conditions, _, _ := unstructured.NestedSlice(run.Object, "status", "conditions")
+	for _, cond := range conditions {
+		condition, ok := cond.(map[string]interface{})
+		if !ok || condition["type"] != "Succeeded" {
+			continue
+		}
+		status, _ := condition["status"].(string)
+		reason, _ := condition["reason"].(string)
+		message, _ := condition["message"].(string)
+		return corev1.ConditionStatus(status), reason, message
+	}
+	return corev1.ConditionUnknown, "", ""
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/e2e-framework/klient"
+)
+
+func TestNewPipelineRun(t *testing.T) {
+	run := newPipelineRun("build-1", "ci", "build", map[string]string{"revision": "main", "image": "app:latest"})
+
+	if run.GetAPIVersion() != "tekton.dev/v1beta1" || run.GetKind() != "PipelineRun" {
+		t.Errorf("unexpected type %s/%s", run.GetAPIVersion(), run.GetKind())
+	}
+	if run.GetName() != "build-1" || run.GetNamespace() != "ci" {
+		t.Errorf("unexpected pipelinerun %s/%s", run.GetNamespace(), run.GetName())
+	}
+	if ref, _, _ := unstructured.NestedString(run.Object, "spec", "pipelineRef", "name"); ref != "build" {
+		t.Errorf("unexpected pipeline ref %q", ref)
+	}
+
+	params, _, _ := unstructured.NestedSlice(run.Object, "spec", "params")
+	expected := []interface{}{
+		map[string]interface{}{"name": "image", "value": "app:latest"},
+		map[string]interface{}{"name": "revision", "value": "main"},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+func TestSucceededCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []interface{}
+		expected   corev1.ConditionStatus
+	}{
+		{name: "no conditions", expected: corev1.ConditionUnknown},
+		{
+			name:       "running",
+			conditions: []interface{}{map[string]interface{}{"type": "Succeeded", "status": "Unknown", "reason": "Running"}},
+			expected:   corev1.ConditionUnknown,
+		},
+		{
+			name:       "succeeded",
+			conditions: []interface{}{map[string]interface{}{"type": "Succeeded", "status": "True", "reason": "Succeeded"}},
+			expected:   corev1.ConditionTrue,
+		},
+		{
+			name:       "failed",
+			conditions: []interface{}{map[string]interface{}{"type": "Succeeded", "status": "False", "reason": "Failed"}},
+			expected:   corev1.ConditionFalse,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run := newPipelineRun("build-1", "ci", "build", nil)
+			if test.conditions != nil {
+				if err := unstructured.SetNestedSlice(run.Object, test.conditions, "status", "conditions"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if status, _, _ := succeededCondition(run); status != test.expected {
+				t.Errorf("expected status %s, got %s", test.expected, status)
+			}
+		})
+	}
+}
+
+// newTektonServer returns an API server serving PipelineRun ci/build-1 with
+// the status, TaskRun ci/build-1-compile and the logs of its pod.
+func newTektonServer(t *testing.T, status map[string]interface{}) *httptest.Server {
+	writeJSON := func(w http.ResponseWriter, obj interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(obj); err != nil {
+			t.Error(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIVersions{Versions: []string{"v1"}})
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"get"}}},
+		})
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, _ *http.Request) {
+		version := metav1.GroupVersionForDiscovery{GroupVersion: "tekton.dev/v1beta1", Version: "v1beta1"}
+		writeJSON(w, &metav1.APIGroupList{Groups: []metav1.APIGroup{{Name: "tekton.dev", Versions: []metav1.GroupVersionForDiscovery{version}, PreferredVersion: version}}})
+	})
+	mux.HandleFunc("/apis/tekton.dev/v1beta1", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &metav1.APIResourceList{
+			GroupVersion: "tekton.dev/v1beta1",
+			APIResources: []metav1.APIResource{
+				{Name: "pipelineruns", Namespaced: true, Kind: "PipelineRun", Verbs: metav1.Verbs{"get"}},
+				{Name: "taskruns", Namespaced: true, Kind: "TaskRun", Verbs: metav1.Verbs{"get"}},
+			},
+		})
+	})
+	mux.HandleFunc("/apis/tekton.dev/v1beta1/namespaces/ci/pipelineruns/build-1", func(w http.ResponseWriter, _ *http.Request) {
+		run := newPipelineRun("build-1", "ci", "build", nil)
+		run.Object["status"] = status
+		writeJSON(w, run.Object)
+	})
+	mux.HandleFunc("/apis/tekton.dev/v1beta1/namespaces/ci/taskruns/build-1-compile", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"apiVersion": "tekton.dev/v1beta1",
+			"kind":       "TaskRun",
+			"metadata":   map[string]interface{}{"name": "build-1-compile", "namespace": "ci"},
+			"status":     map[string]interface{}{"podName": "build-1-compile-pod"},
+		})
+	})
+	mux.HandleFunc("/api/v1/namespaces/ci/pods/build-1-compile-pod", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "build-1-compile-pod", Namespace: "ci"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "step-build"}}},
+		})
+	})
+	mux.HandleFunc("/api/v1/namespaces/ci/pods/build-1-compile-pod/log", func(w http.ResponseWriter, r *http.Request) {
+		if container := r.URL.Query().Get("container"); container != "step-build" {
+			t.Errorf("unexpected logs container %q", container)
+		}
+		_, _ = w.Write([]byte("build succeeded"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestTektonManager_GetPipelineRunLogs(t *testing.T) {
+	tests := []struct {
+		name   string
+		status map[string]interface{}
+	}{
+		{
+			name: "child references",
+			status: map[string]interface{}{
+				"childReferences": []interface{}{
+					map[string]interface{}{"apiVersion": "tekton.dev/v1beta1", "kind": "TaskRun", "name": "build-1-compile", "pipelineTaskName": "compile"},
+					map[string]interface{}{"apiVersion": "tekton.dev/v1alpha1", "kind": "Run", "name": "build-1-approve", "pipelineTaskName": "approve"},
+				},
+			},
+		},
+		{
+			name: "embedded taskrun statuses",
+			status: map[string]interface{}{
+				"taskRuns": map[string]interface{}{
+					"build-1-compile": map[string]interface{}{
+						"pipelineTaskName": "compile",
+						"status":           map[string]interface{}{"podName": "build-1-compile-pod"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTektonServer(t, test.status)
+			defer server.Close()
+
+			client, err := klient.New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			logs, err := NewTektonManager(client).GetPipelineRunLogs(context.TODO(), "build-1", "ci")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := map[string]string{"build-1-compile/step-build": "build succeeded"}
+			if !reflect.DeepEqual(logs, expected) {
+				t.Errorf("expected logs %v, got %v", expected, logs)
+			}
+		})
+	}
+}
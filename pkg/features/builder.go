@@ -17,7 +17,9 @@ limitations under the License.
 package features
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"sigs.k8s.io/e2e-framework/pkg/internal/types"
 )
@@ -38,6 +40,54 @@ func (b *FeatureBuilder) WithLabel(key, value string) *FeatureBuilder {
 	return b
 }
 
+// WithLabels adds the test label key/value pairs
+func (b *FeatureBuilder) WithLabels(labels map[string]string) *FeatureBuilder {
+	for key, value := range labels {
+		b.feat.labels[key] = value
+	}
+	return b
+}
+
+// WithOrder sets the ordering key of the feature. When tested with
+// env.Test, features are sorted by ascending order value and features
+// with the same order value are tested in the order they were passed.
+// Features are created with an order value of 0.
+//
+// Features with the same order value that declare a common context key (see
+// WithContextKeys) are reported when tested, as potential data races: their
+// relative order only depends on how they were passed.
+func (b *FeatureBuilder) WithOrder(n int) *FeatureBuilder {
+	b.feat.order = n
+	return b
+}
+
+// WithContextKeys declares the context keys read or written by the feature
+// steps, which are checked against the keys of the features with the same
+// order value (see WithOrder). Keys must be comparable, as with
+// context.WithValue.
+func (b *FeatureBuilder) WithContextKeys(keys ...interface{}) *FeatureBuilder {
+	b.feat.keys = append(b.feat.keys, keys...)
+	return b
+}
+
+// WithBaseContext sets the context the feature is tested with, instead of the
+// environment's context, i.e. a context holding the client of another cluster.
+// The context updated by the feature steps is not propagated to the environment
+// and the environment's context values and deadline do not apply to the feature.
+func (b *FeatureBuilder) WithBaseContext(ctx context.Context) *FeatureBuilder {
+	b.feat.baseCtx = ctx
+	return b
+}
+
+// WithTimeout sets the maximum duration of the feature test. The feature
+// steps are executed with a context that expires after d: once it expires,
+// the remaining assessments are skipped, the feature fails and the teardown
+// steps are executed with a context that is not expired.
+func (b *FeatureBuilder) WithTimeout(d time.Duration) *FeatureBuilder {
+	b.feat.timeout = d
+	return b
+}
+
 // Setup adds a new setup step that will be applied prior to feature test.
 func (b *FeatureBuilder) Setup(fn Func) *FeatureBuilder {
 	b.feat.steps = append(b.feat.steps, newStep(fmt.Sprintf("%s-setup", b.feat.name), types.LevelSetup, fn))
@@ -56,6 +106,49 @@ func (b *FeatureBuilder) Assess(desc string, fn Func) *FeatureBuilder {
 	return b
 }
 
+// SetupWithTB adds a setup step, like Setup, with a function that takes a
+// testing.TB, so that the step also runs when the feature is benchmarked.
+func (b *FeatureBuilder) SetupWithTB(fn FuncTB) *FeatureBuilder {
+	b.feat.steps = append(b.feat.steps, newStepTB(fmt.Sprintf("%s-setup", b.feat.name), types.LevelSetup, fn))
+	return b
+}
+
+// TeardownWithTB adds a teardown step, like Teardown, with a function that takes
+// a testing.TB, so that the step also runs when the feature is benchmarked.
+func (b *FeatureBuilder) TeardownWithTB(fn FuncTB) *FeatureBuilder {
+	b.feat.steps = append(b.feat.steps, newStepTB(fmt.Sprintf("%s-teardown", b.feat.name), types.LevelTeardown, fn))
+	return b
+}
+
+// AssessWithTB adds an assessment step, like Assess, with a function that takes
+// a testing.TB, so that the step also runs when the feature is benchmarked.
+func (b *FeatureBuilder) AssessWithTB(desc string, fn FuncTB) *FeatureBuilder {
+	b.feat.steps = append(b.feat.steps, newStepTB(desc, types.LevelAssess, fn))
+	return b
+}
+
+// Parallel marks the last added assessment to run concurrently with the
+// parallel assessments adjacent to it, i.e. Assess("name", fn).Parallel().
+// Adjacent parallel assessments run as subtests calling t.Parallel, grouped
+// in a feature/parallel subtest, and the next assessment waits for them.
+// It has no effect when the last added step is not an assessment.
+func (b *FeatureBuilder) Parallel() *FeatureBuilder {
+	if len(b.feat.steps) == 0 {
+		return b
+	}
+	if step, ok := b.feat.steps[len(b.feat.steps)-1].(*testStep); ok && step.level == types.LevelAssess {
+		step.parallel = true
+	}
+	return b
+}
+
+// AssessInParallel adds an assessment step that runs concurrently with the
+// parallel assessments adjacent to it, like Assess(desc, fn).Parallel().
+// Each parallel assessment runs in its own subtest, so fn may call t.Fatal.
+func (b *FeatureBuilder) AssessInParallel(desc string, fn Func) *FeatureBuilder {
+	return b.Assess(desc, fn).Parallel()
+}
+
 // Feature returns a feature configured by builder.
 func (b *FeatureBuilder) Feature() types.Feature {
 	return b.feat
@@ -67,6 +67,93 @@ func TestFeatureBuilder(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "with labels map",
+			setup: func(t *testing.T) types.Feature {
+				return New("test").WithLabel("a", "b").WithLabels(map[string]string{"a": "c", "d": "e"}).Feature()
+			},
+			eval: func(t *testing.T, f types.Feature) {
+				ft := f.(*defaultFeature) // nolint
+				if len(ft.labels) != 2 {
+					t.Error("unexpected labels len:", len(ft.labels))
+				}
+				if ft.labels["a"] != "c" {
+					t.Error("unexpected label value:", ft.labels["a"])
+				}
+			},
+		},
+		{
+			name: "with context keys",
+			setup: func(t *testing.T) types.Feature {
+				return New("test").WithOrder(1).WithContextKeys("a").WithContextKeys("b", 3).Feature()
+			},
+			eval: func(t *testing.T, f types.Feature) {
+				keys := f.ContextKeys()
+				if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != 3 {
+					t.Error("unexpected context keys:", keys)
+				}
+				if f.Order() != 1 {
+					t.Error("unexpected order:", f.Order())
+				}
+			},
+		},
+		{
+			name: "testing.TB steps",
+			setup: func(t *testing.T) types.Feature {
+				noop := func(ctx context.Context, tb testing.TB, _ *envconf.Config) context.Context { return ctx }
+				return New("test").SetupWithTB(noop).AssessWithTB("assess", noop).TeardownWithTB(noop).
+					Assess("requires t", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }).Feature()
+			},
+			eval: func(t *testing.T, f types.Feature) {
+				steps := f.Steps()
+				if len(steps) != 4 {
+					t.Fatal("unexpected number of steps:", len(steps))
+				}
+				for _, step := range steps[:3] {
+					if step.FuncTB() == nil || step.Func() == nil {
+						t.Errorf("step %s should have both step functions", step.Name())
+					}
+				}
+				if steps[3].FuncTB() != nil {
+					t.Error("unexpected testing.TB function for step", steps[3].Name())
+				}
+				if steps[1].Level() != types.LevelAssess || steps[1].Func()(context.TODO(), t, nil) == nil {
+					t.Error("unexpected assessment step", steps[1].Name())
+				}
+			},
+		},
+		{
+			name: "parallel assessment",
+			setup: func(t *testing.T) types.Feature {
+				noop := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }
+				return New("test").Setup(noop).Parallel().Assess("parallel", noop).Parallel().Assess("sequential", noop).Feature()
+			},
+			eval: func(t *testing.T, f types.Feature) {
+				steps := f.Steps()
+				if steps[0].Parallel() {
+					t.Error("setup step should not be parallel")
+				}
+				if !steps[1].Parallel() || steps[2].Parallel() {
+					t.Error("only the assessment followed by Parallel should be parallel")
+				}
+			},
+		},
+		{
+			name: "assessment in parallel",
+			setup: func(t *testing.T) types.Feature {
+				noop := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }
+				return New("test").AssessInParallel("parallel", noop).Assess("sequential", noop).Feature()
+			},
+			eval: func(t *testing.T, f types.Feature) {
+				steps := f.Steps()
+				if steps[0].Name() != "parallel" || steps[0].Level() != types.LevelAssess || !steps[0].Parallel() {
+					t.Error("expected a parallel assessment")
+				}
+				if steps[1].Parallel() {
+					t.Error("only the assessment added with AssessInParallel should be parallel")
+				}
+			},
+		},
 		{
 			name: "one setup",
 			setup: func(t *testing.T) types.Feature {
@@ -17,8 +17,12 @@ limitations under the License.
 package features
 
 import (
+	"context"
 	"regexp"
+	"testing"
+	"time"
 
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/internal/types"
 )
 
@@ -27,13 +31,18 @@ type (
 	Feature = types.Feature
 	Step    = types.Step
 	Func    = types.StepFunc
+	FuncTB  = types.StepFuncTB
 	Level   = types.Level
 )
 
 type defaultFeature struct {
-	name   string
-	labels types.Labels
-	steps  []types.Step
+	name    string
+	labels  types.Labels
+	steps   []types.Step
+	order   int
+	keys    []interface{}
+	baseCtx context.Context
+	timeout time.Duration
 }
 
 func newDefaultFeature(name string) *defaultFeature {
@@ -52,10 +61,28 @@ func (f *defaultFeature) Steps() []types.Step {
 	return f.steps
 }
 
+func (f *defaultFeature) Order() int {
+	return f.order
+}
+
+func (f *defaultFeature) ContextKeys() []interface{} {
+	return f.keys
+}
+
+func (f *defaultFeature) BaseContext() context.Context {
+	return f.baseCtx
+}
+
+func (f *defaultFeature) Timeout() time.Duration {
+	return f.timeout
+}
+
 type testStep struct {
-	name  string
-	level Level
-	fn    Func
+	name     string
+	level    Level
+	fn       Func
+	fnTB     FuncTB
+	parallel bool
 }
 
 func newStep(name string, level Level, fn Func) *testStep {
@@ -66,6 +93,19 @@ func newStep(name string, level Level, fn Func) *testStep {
 	}
 }
 
+// newStepTB returns a step running fn, with a *testing.T when
+// tested and a *testing.B when benchmarked
+func newStepTB(name string, level Level, fn FuncTB) *testStep {
+	return &testStep{
+		name:  name,
+		level: level,
+		fn: func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			return fn(ctx, t, cfg)
+		},
+		fnTB: fn,
+	}
+}
+
 func (s *testStep) Name() string {
 	return s.name
 }
@@ -78,6 +118,14 @@ func (s *testStep) Func() Func {
 	return s.fn
 }
 
+func (s *testStep) FuncTB() FuncTB {
+	return s.fnTB
+}
+
+func (s *testStep) Parallel() bool {
+	return s.parallel
+}
+
 func GetStepsByLevel(steps []types.Step, l types.Level) []types.Step {
 	if steps == nil {
 		return nil
@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// FeatureCase is a case of a table-driven feature (see Table). Its steps,
+// which are optional, receive the case's parameters in their context; the
+// parameters are retrieved with ParamsFromContext.
+type FeatureCase struct {
+	Name     string
+	Params   interface{}
+	Setup    Func
+	Assess   Func
+	Teardown Func
+}
+
+// paramsKey is the context key of the parameters of a feature case
+type paramsKey struct{}
+
+// Table returns a feature builder for each case, named <name>/<case name>.
+// The steps of each case are executed with the case's parameters in their
+// context. The builders can be completed with additional steps or labels
+// before being built, i.e. using TableFeatures.
+func Table(name string, cases []FeatureCase) []*FeatureBuilder {
+	builders := make([]*FeatureBuilder, 0, len(cases))
+	for _, c := range cases {
+		b := New(fmt.Sprintf("%s/%s", name, c.Name))
+		if c.Setup != nil {
+			b.Setup(withParams(c.Params, c.Setup))
+		}
+		if c.Assess != nil {
+			b.Assess(c.Name, withParams(c.Params, c.Assess))
+		}
+		if c.Teardown != nil {
+			b.Teardown(withParams(c.Params, c.Teardown))
+		}
+		builders = append(builders, b)
+	}
+	return builders
+}
+
+// TableFeatures returns the features of the builders, so that the
+// features of a table can be passed to env.Test:
+//
+//	testenv.Test(t, features.TableFeatures(features.Table("name", cases))...)
+func TableFeatures(builders []*FeatureBuilder) []types.Feature {
+	feats := make([]types.Feature, 0, len(builders))
+	for _, b := range builders {
+		feats = append(feats, b.Feature())
+	}
+	return feats
+}
+
+// ParamsFromContext returns the parameters of the feature case
+// (see Table) being tested, or nil when ctx has none.
+func ParamsFromContext(ctx context.Context) interface{} {
+	return ctx.Value(paramsKey{})
+}
+
+// withParams returns a step func that runs fn with params in its context.
+// The params are scoped to fn: the context it returns, which is passed on to
+// the next steps and features, holds the parameters of ctx instead, if any.
+// A nil context returned by fn is returned as is.
+func withParams(params interface{}, fn Func) Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		out := fn(context.WithValue(ctx, paramsKey{}, params), t, cfg)
+		if out == nil {
+			return nil
+		}
+		return &paramsContext{Context: out, params: ParamsFromContext(ctx)}
+	}
+}
+
+// paramsContext overrides the feature case parameters of the embedded context.
+type paramsContext struct {
+	context.Context
+	params interface{}
+}
+
+func (c *paramsContext) Value(key interface{}) interface{} {
+	if _, ok := key.(paramsKey); ok {
+		return c.params
+	}
+	return c.Context.Value(key)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+func TestTable_StepContext(t *testing.T) {
+	type ctxKey struct{}
+	tests := []struct {
+		name   string
+		assess Func
+		check  func(*testing.T, context.Context)
+	}{
+		{
+			name: "params scoped to the step",
+			assess: func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				if params := ParamsFromContext(ctx); params != "case params" {
+					t.Errorf("expected the case params in the step context, got %v", params)
+				}
+				return context.WithValue(ctx, ctxKey{}, "value")
+			},
+			check: func(t *testing.T, ctx context.Context) {
+				if params := ParamsFromContext(ctx); params != "outer params" {
+					t.Errorf("expected the outer params in the returned context, got %v", params)
+				}
+				if value := ctx.Value(ctxKey{}); value != "value" {
+					t.Errorf("expected the value set by the step in the returned context, got %v", value)
+				}
+			},
+		},
+		{
+			name: "nil context",
+			assess: func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				return nil
+			},
+			check: func(t *testing.T, ctx context.Context) {
+				if ctx != nil {
+					t.Errorf("expected the nil context to be returned as is, got %v", ctx)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builders := Table("table", []FeatureCase{{Name: "case", Params: "case params", Assess: test.assess}})
+			steps := GetStepsByLevel(builders[0].Feature().Steps(), types.LevelAssess)
+			if len(steps) != 1 {
+				t.Fatalf("expected 1 assessment, got %d", len(steps))
+			}
+
+			ctx := context.WithValue(context.Background(), paramsKey{}, "outer params")
+			test.check(t, steps[0].Func()(ctx, t, envconf.New()))
+		})
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -29,6 +31,7 @@ const (
 	flagFeatureName   = "feature"
 	flagAssessName    = "assess"
 	flagLabelsName    = "labels"
+	flagFailFastName  = "fail-fast"
 )
 
 // Supported flag definitions
@@ -53,6 +56,10 @@ var (
 		Name:  flagNamespaceName,
 		Usage: "A namespace value to use for testing (optional)",
 	}
+	failFastFlag = flag.Flag{
+		Name:  flagFailFastName,
+		Usage: "Stop running the remaining features of a test after the first feature failure",
+	}
 )
 
 // EnvFlags surfaces all resolved flag values for the testing framework
@@ -61,6 +68,8 @@ type EnvFlags struct {
 	assess  string
 	labels  LabelsMap
 
+	failFast bool
+
 	// optional kube flags
 	kubeconfig string
 	namespace  string
@@ -91,6 +100,11 @@ func (f *EnvFlags) Kubeconfig() string {
 	return f.kubeconfig
 }
 
+// FailFast returns value for `-fail-fast` flag
+func (f *EnvFlags) FailFast() bool {
+	return f.failFast
+}
+
 // Parse parses defined CLI args os.Args[1:]
 func Parse() (*EnvFlags, error) {
 	return ParseArgs(os.Args[1:])
@@ -104,6 +118,7 @@ func ParseArgs(args []string) (*EnvFlags, error) {
 	labels := make(LabelsMap)
 	var namespace string
 	var kubeconfig string
+	var failFast bool
 
 	if flag.Lookup(featureFlag.Name) == nil {
 		flag.StringVar(&feature, featureFlag.Name, featureFlag.DefValue, featureFlag.Usage)
@@ -125,11 +140,31 @@ func ParseArgs(args []string) (*EnvFlags, error) {
 		flag.Var(&labels, labelsFlag.Name, labelsFlag.Usage)
 	}
 
+	if flag.Lookup(failFastFlag.Name) == nil {
+		flag.BoolVar(&failFast, failFastFlag.Name, false, failFastFlag.Usage)
+	}
+
 	if err := flag.CommandLine.Parse(args); err != nil {
 		return nil, fmt.Errorf("flags parsing: %w", err)
 	}
 
-	return &EnvFlags{feature: feature, assess: assess, labels: labels, namespace: namespace, kubeconfig: kubeconfig}, nil
+	if err := validateNamespace(namespace); err != nil {
+		return nil, fmt.Errorf("flags parsing: %w", err)
+	}
+
+	return &EnvFlags{feature: feature, assess: assess, labels: labels, namespace: namespace, kubeconfig: kubeconfig, failFast: failFast}, nil
+}
+
+// validateNamespace returns an error when name is set but is not a valid
+// Kubernetes namespace name (a DNS-1123 label).
+func validateNamespace(name string) error {
+	if name == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid %s %q: %s", flagNamespaceName, name, strings.Join(errs, "; "))
+	}
+	return nil
 }
 
 type LabelsMap map[string]string
@@ -17,6 +17,7 @@ limitations under the License.
 package flags
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -28,8 +29,8 @@ func TestParseFlags(t *testing.T) {
 	}{
 		{
 			name:  "with all",
-			args:  []string{"-assess", "volume test", "--feature", "beta", "--labels", "k0=v0, k1=v1, k2=v2"},
-			flags: &EnvFlags{assess: "volume test", feature: "beta", labels: LabelsMap{"k0": "v0", "k1": "v1", "k2": "v2"}},
+			args:  []string{"-assess", "volume test", "--feature", "beta", "--labels", "k0=v0, k1=v1, k2=v2", "--fail-fast"},
+			flags: &EnvFlags{assess: "volume test", feature: "beta", labels: LabelsMap{"k0": "v0", "k1": "v1", "k2": "v2"}, failFast: true},
 		},
 	}
 
@@ -45,6 +46,9 @@ func TestParseFlags(t *testing.T) {
 			if testFlags.Assessment() != test.flags.Assessment() {
 				t.Errorf("unmatched assessment: %s", testFlags.Assessment())
 			}
+			if testFlags.FailFast() != test.flags.FailFast() {
+				t.Errorf("unmatched fail-fast: %t", testFlags.FailFast())
+			}
 
 			for k, v := range testFlags.Labels() {
 				if test.flags.Labels()[k] != v {
@@ -54,3 +58,34 @@ func TestParseFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateNamespace(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		valid     bool
+	}{
+		{name: "empty", namespace: "", valid: true},
+		{name: "single char", namespace: "a", valid: true},
+		{name: "dashes and digits", namespace: "e2e-test-01", valid: true},
+		{name: "max length", namespace: strings.Repeat("a", 63), valid: true},
+		{name: "upper case", namespace: "E2E"},
+		{name: "underscore", namespace: "e2e_test"},
+		{name: "dot", namespace: "e2e.test"},
+		{name: "leading dash", namespace: "-e2e"},
+		{name: "trailing dash", namespace: "e2e-"},
+		{name: "too long", namespace: strings.Repeat("a", 64)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateNamespace(test.namespace)
+			if test.valid && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if !test.valid && err == nil {
+				t.Errorf("expected namespace %q to be rejected", test.namespace)
+			}
+		})
+	}
+}
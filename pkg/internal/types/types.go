@@ -19,7 +19,10 @@ package types
 import (
 	"context"
 	"testing"
+	"time"
 
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/e2e-framework/klient"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 )
 
@@ -29,20 +32,71 @@ import (
 // to caller.
 type EnvFunc func(context.Context, *envconf.Config) (context.Context, error)
 
+// FeatureEnvFunc represents a user-defined operation that is executed
+// before or after a feature is tested. It receives the *testing.T of
+// the feature's subtest.
+type FeatureEnvFunc func(context.Context, *testing.T, *envconf.Config) (context.Context, error)
+
+// AfterAssessmentFunc represents a user-defined operation that is
+// executed after each assessment completes. It receives the names of the
+// feature and the assessment along with the assessment's pass/fail status.
+type AfterAssessmentFunc func(ctx context.Context, cfg *envconf.Config, featureName, assessmentName string, passed bool) (context.Context, error)
+
+// BeforeTestWithCleanupFunc represents a user-defined operation that is
+// executed before each test. It receives the test's *testing.T and returns
+// an optional cleanup func that is registered using t.Cleanup.
+type BeforeTestWithCleanupFunc func(context.Context, *envconf.Config, *testing.T) (context.Context, func(), error)
+
 // Environment represents an environment where
 // features can be tested.
 type Environment interface {
 	// WithContext returns a new Environment with a new context
 	WithContext(context.Context) Environment
 
+	// WithContextTimeout sets a suite-wide deadline on the environment's context
+	WithContextTimeout(time.Duration) Environment
+
+	// WithSuiteTimeout sets a deadline for the whole test suite,
+	// starting when Run is called
+	WithSuiteTimeout(time.Duration) Environment
+
+	// WithK8SVersionConstraint skips the test suite when the version
+	// of the cluster does not satisfy the constraint, i.e. ">=1.24, <1.29"
+	WithK8SVersionConstraint(constraint string) Environment
+
+	// WithGlobalLabels sets labels that are merged with the labels of
+	// each tested feature. Feature labels take precedence over global labels.
+	WithGlobalLabels(map[string]string) Environment
+
 	// Setup registers environment operations that are executed once
 	// prior to the environment being ready and prior to any test.
 	Setup(...EnvFunc) Environment
 
+	// SetupIf registers setup operations that are only
+	// executed when condition returns true
+	SetupIf(condition func(context.Context, *envconf.Config) bool, funcs ...EnvFunc) Environment
+
+	// SetupWithRollback registers a setup operation paired with the
+	// operation that undoes it, executed when a later setup fails
+	SetupWithRollback(fn EnvFunc, rollback EnvFunc) Environment
+
 	// BeforeEachTest registers environment funcs that are executed
 	// before each Env.Test(...)
 	BeforeEachTest(...EnvFunc) Environment
 
+	// BeforeEachTestIf registers funcs executed before each
+	// Env.Test(...) when condition returns true
+	BeforeEachTestIf(condition func(context.Context, *envconf.Config) bool, funcs ...EnvFunc) Environment
+
+	// BeforeEachTestWithCleanup registers funcs that are executed before
+	// each Env.Test(...) and that can return a cleanup func registered
+	// with the test's t.Cleanup.
+	BeforeEachTestWithCleanup(...BeforeTestWithCleanupFunc) Environment
+
+	// WithRandomTestNamespace creates, before each test, a namespace with a
+	// random name starting with prefix, which is deleted after the test
+	WithRandomTestNamespace(prefix string) Environment
+
 	// BeforeEachFeature registers step functions that are executed
 	// before each Feature is tested during env.Test call.
 	BeforeEachFeature(...EnvFunc) Environment
@@ -51,20 +105,79 @@ type Environment interface {
 	// after each feature is tested during an env.Test call.
 	AfterEachFeature(...EnvFunc) Environment
 
+	// BeforeEachFeatureWithT registers funcs that are executed before
+	// each feature is tested, using the feature's *testing.T.
+	BeforeEachFeatureWithT(...FeatureEnvFunc) Environment
+
+	// AfterEachFeatureWithT registers funcs that are executed after
+	// each feature is tested, using the feature's *testing.T.
+	AfterEachFeatureWithT(...FeatureEnvFunc) Environment
+
 	// Test executes a test feature defined in a TestXXX function
 	// This method surfaces context for further updates.
 	Test(*testing.T, ...Feature)
 
+	// TestInParallel executes test features concurrently, with
+	// a concurrency limit set by the environment configuration
+	TestInParallel(*testing.T, ...Feature)
+
+	// TestWithDeadline executes a test feature, like Test, using a context
+	// that is cancelled when either the environment's context or the
+	// provided context is done.
+	TestWithDeadline(context.Context, *testing.T, ...Feature)
+
+	// Benchmark executes test features defined in a BenchmarkXXX function,
+	// running the assessments of each feature b.N times.
+	Benchmark(*testing.B, ...Feature)
+
 	// AfterEachTest registers environment funcs that are executed
 	// after each Env.Test(...).
 	AfterEachTest(...EnvFunc) Environment
 
+	// AfterEachTestIf registers funcs executed after each
+	// Env.Test(...) when condition returns true
+	AfterEachTestIf(condition func(context.Context, *envconf.Config) bool, funcs ...EnvFunc) Environment
+
+	// AfterEachAssessment registers funcs that are executed after
+	// each assessment of a feature, during an env.Test call.
+	AfterEachAssessment(...AfterAssessmentFunc) Environment
+
 	// Finish registers funcs that are executed at the end of the
 	// test suite.
 	Finish(...EnvFunc) Environment
 
+	// FinishIf registers finish funcs that are only
+	// executed when condition returns true
+	FinishIf(condition func(context.Context, *envconf.Config) bool, funcs ...EnvFunc) Environment
+
+	// Clone returns a copy of the environment, with a deep copy of its
+	// configuration, whose actions can be changed independently.
+	Clone() Environment
+
+	// Middleware registers a func that wraps every environment func
+	// registered as an action, to inject cross-cutting concerns such
+	// as logging or metrics. Middlewares compose in registration order.
+	Middleware(func(EnvFunc) EnvFunc) Environment
+
+	// WithEventRecorder sets the recorder used to publish Kubernetes
+	// events when the setup, features and assessments start or fail.
+	WithEventRecorder(record.EventRecorder) Environment
+
+	// WithEventSink publishes the environment events (see WithEventRecorder)
+	// to the namespace using a recorder backed by the client.
+	WithEventSink(client klient.Client, namespace string) Environment
+
+	// Describe returns a human-readable summary of the registered
+	// actions and of the features that would be tested, without
+	// executing anything.
+	Describe(...Feature) string
+
 	// Run Launches the test suite from within a TestMain
 	Run(*testing.M) int
+
+	// RunSubset launches the test suite from within a TestMain, only
+	// running the tests with names for which the predicate returns true.
+	RunSubset(*testing.M, func(testName string) bool) int
 }
 
 type Labels map[string]string
@@ -76,6 +189,16 @@ type Feature interface {
 	Labels() Labels
 	// Steps testing tasks to test the feature
 	Steps() []Step
+	// Order is the ordering key used to sort features before they are tested
+	Order() int
+	// ContextKeys are the context keys declared as read or written by
+	// the feature steps, nil when not declared
+	ContextKeys() []interface{}
+	// BaseContext is the context the feature is tested with instead of
+	// the environment's context, nil when not set
+	BaseContext() context.Context
+	// Timeout is the maximum duration of the feature test, 0 when not set
+	Timeout() time.Duration
 }
 
 type Level uint8
@@ -91,6 +214,10 @@ const (
 
 type StepFunc func(context.Context, *testing.T, *envconf.Config) context.Context
 
+// StepFuncTB is a step function that runs with either a *testing.T or,
+// when benchmarking, a *testing.B
+type StepFuncTB func(context.Context, testing.TB, *envconf.Config) context.Context
+
 type Step interface {
 	// Name is the step name
 	Name() string
@@ -98,4 +225,10 @@ type Step interface {
 	Level() Level
 	// Func is the operation for the step
 	Func() StepFunc
+	// FuncTB is the operation for the step when registered as a
+	// StepFuncTB, which benchmarks can run, nil otherwise
+	FuncTB() StepFuncTB
+	// Parallel reports whether the step runs concurrently
+	// with the adjacent parallel steps
+	Parallel() bool
 }
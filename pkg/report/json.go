@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+)
+
+// TestEvent is a test result event, with the structure
+// of the events emitted by go test -json.
+type TestEvent struct {
+	Time    time.Time
+	Action  string
+	Package string   `json:",omitempty"`
+	Test    string   `json:",omitempty"`
+	Elapsed *float64 `json:",omitempty"`
+}
+
+// JSONReporter writes the results, as go test -json events, to a file with
+// one JSON event per line. Features are reported as tests and assessments as
+// their subtests (i.e. feature/assessment). The file is rewritten each time a
+// feature ends.
+type JSONReporter struct {
+	path    string
+	pkg     string
+	mu      sync.Mutex
+	events  []TestEvent
+	started map[string]time.Time
+}
+
+// NewJSONReporter returns a JSONReporter that writes to the file at path.
+func NewJSONReporter(path string) *JSONReporter {
+	return &JSONReporter{path: path, started: make(map[string]time.Time)}
+}
+
+// WithPackage sets the package of the reported events.
+func (r *JSONReporter) WithPackage(pkg string) *JSONReporter {
+	r.pkg = pkg
+	return r
+}
+
+// OnFeatureStart records a run event for the feature.
+func (r *JSONReporter) OnFeatureStart(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.started[name] = now
+	r.events = append(r.events, TestEvent{Time: now, Action: "run", Package: r.pkg, Test: name})
+}
+
+// OnAssessmentResult records a run event, dated from the start of the
+// assessment, followed by a pass or fail event for the assessment.
+func (r *JSONReporter) OnAssessmentResult(feature, assessment string, passed bool, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	test := feature + "/" + assessment
+	result := r.resultEvent(test, passed, duration)
+	r.events = append(r.events,
+		TestEvent{Time: result.Time.Add(-duration), Action: "run", Package: r.pkg, Test: test},
+		result,
+	)
+}
+
+// OnFeatureEnd records a pass or fail event for the feature
+// and writes the recorded events to the file.
+func (r *JSONReporter) OnFeatureEnd(name string, passed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var duration time.Duration
+	if start, ok := r.started[name]; ok {
+		duration = time.Since(start)
+		delete(r.started, name)
+	}
+	r.events = append(r.events, r.resultEvent(name, passed, duration))
+
+	if err := r.write(); err != nil {
+		log.Printf("JSON reporter: %s", err)
+	}
+}
+
+func (r *JSONReporter) resultEvent(test string, passed bool, duration time.Duration) TestEvent {
+	action := "pass"
+	if !passed {
+		action = "fail"
+	}
+	elapsed := duration.Seconds()
+	return TestEvent{Time: time.Now(), Action: action, Package: r.pkg, Test: test, Elapsed: &elapsed}
+}
+
+func (r *JSONReporter) write() error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range r.events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(r.path, buf.Bytes(), 0644)
+}
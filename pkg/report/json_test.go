@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	r := NewJSONReporter(path).WithPackage("example.com/e2e")
+
+	r.OnFeatureStart("feature")
+	r.OnAssessmentResult("feature", "passing", true, time.Second)
+	r.OnAssessmentResult("feature", "failing", false, 2*time.Second)
+	r.OnFeatureEnd("feature", false)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one event per line, as go test -json
+	var events []TestEvent
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var event TestEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event line %q: %s", line, err)
+		}
+		events = append(events, event)
+	}
+
+	expected := []struct {
+		action  string
+		test    string
+		elapsed float64
+	}{
+		{action: "run", test: "feature"},
+		{action: "run", test: "feature/passing"},
+		{action: "pass", test: "feature/passing", elapsed: 1},
+		{action: "run", test: "feature/failing"},
+		{action: "fail", test: "feature/failing", elapsed: 2},
+		{action: "fail", test: "feature"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if event.Action != expected[i].action || event.Test != expected[i].test || event.Package != "example.com/e2e" {
+			t.Errorf("unexpected event %d: %+v", i, event)
+		}
+		if expected[i].elapsed > 0 && (event.Elapsed == nil || *event.Elapsed != expected[i].elapsed) {
+			t.Errorf("unexpected elapsed time of event %d: %v", i, event.Elapsed)
+		}
+	}
+}
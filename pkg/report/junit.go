@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// JUnitReporter writes the results, as JUnit XML, to a file. Each feature is
+// reported as a test suite, with its assessments as test cases. The file is
+// rewritten each time a feature ends.
+type JUnitReporter struct {
+	path    string
+	mu      sync.Mutex
+	suites  []junitTestSuite
+	running map[string]*runningSuite
+}
+
+// runningSuite is a test suite of a feature being tested
+type runningSuite struct {
+	start time.Time
+	cases []junitTestCase
+}
+
+// NewJUnitReporter returns a JUnitReporter that writes to the file at path.
+func NewJUnitReporter(path string) *JUnitReporter {
+	return &JUnitReporter{path: path, running: make(map[string]*runningSuite)}
+}
+
+// OnFeatureStart starts the test suite of the feature.
+func (r *JUnitReporter) OnFeatureStart(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.running[name] = &runningSuite{start: time.Now()}
+}
+
+// OnAssessmentResult adds the test case of the assessment
+// to the test suite of the feature.
+func (r *JUnitReporter) OnAssessmentResult(feature, assessment string, passed bool, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	suite, ok := r.running[feature]
+	if !ok {
+		suite = &runningSuite{start: time.Now()}
+		r.running[feature] = suite
+	}
+
+	testCase := junitTestCase{Name: assessment, ClassName: feature, Time: seconds(duration)}
+	if !passed {
+		testCase.Failure = &junitFailure{Message: fmt.Sprintf("assessment %q failed", assessment)}
+	}
+	suite.cases = append(suite.cases, testCase)
+}
+
+// OnFeatureEnd completes the test suite of the feature
+// and writes the test suites to the file.
+func (r *JUnitReporter) OnFeatureEnd(name string, passed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	running, ok := r.running[name]
+	if !ok {
+		running = &runningSuite{start: time.Now()}
+	}
+	delete(r.running, name)
+
+	suite := junitTestSuite{
+		Name:      name,
+		Tests:     len(running.cases),
+		Time:      seconds(time.Since(running.start)),
+		Timestamp: running.start.Format(time.RFC3339),
+		TestCases: running.cases,
+	}
+	for _, testCase := range running.cases {
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+	}
+	if !passed && suite.Failures == 0 {
+		// the feature failed outside of its assessments, i.e. in a setup
+		suite.Tests++
+		suite.Failures++
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name: name, ClassName: name, Time: suite.Time,
+			Failure: &junitFailure{Message: fmt.Sprintf("feature %q failed", name)},
+		})
+	}
+	r.suites = append(r.suites, suite)
+
+	if err := r.write(); err != nil {
+		log.Printf("JUnit reporter: %s", err)
+	}
+}
+
+func (r *JUnitReporter) write() error {
+	report := junitTestSuites{Suites: r.suites}
+	for _, suite := range r.suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append([]byte(xml.Header), data...), 0644)
+}
+
+// seconds formats the duration as a number of seconds
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJUnitReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.xml")
+	r := NewJUnitReporter(path)
+
+	r.OnFeatureStart("passing feature")
+	r.OnAssessmentResult("passing feature", "first", true, time.Second)
+	r.OnFeatureEnd("passing feature", true)
+
+	r.OnFeatureStart("failing feature")
+	r.OnAssessmentResult("failing feature", "first", true, time.Second)
+	r.OnAssessmentResult("failing feature", "second", false, 1500*time.Millisecond)
+	r.OnFeatureEnd("failing feature", false)
+
+	r.OnFeatureStart("failing setup")
+	r.OnFeatureEnd("failing setup", false)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Tests != 4 || report.Failures != 2 {
+		t.Errorf("expected 4 tests and 2 failures, got %d tests and %d failures", report.Tests, report.Failures)
+	}
+	if len(report.Suites) != 3 {
+		t.Fatalf("expected 3 test suites, got %d", len(report.Suites))
+	}
+
+	failing := report.Suites[1]
+	if failing.Name != "failing feature" || failing.Tests != 2 || failing.Failures != 1 {
+		t.Errorf("unexpected test suite %+v", failing)
+	}
+	second := failing.TestCases[1]
+	if second.Name != "second" || second.ClassName != "failing feature" || second.Time != "1.500" || second.Failure == nil {
+		t.Errorf("unexpected test case %+v", second)
+	}
+
+	if setup := report.Suites[2]; setup.Tests != 1 || setup.Failures != 1 {
+		t.Errorf("expected a failed test case for the feature failing outside of assessments, got %+v", setup)
+	}
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package report provides reporters that record the results of
+// the features and assessments tested by an environment, i.e. to
+// publish them to CI systems.
+package report
+
+import "time"
+
+// Reporter receives the results of the tested features and assessments. A
+// reporter is registered using envconf.Config.WithReporter. Its methods can
+// be called concurrently, when features or assessments are tested in parallel.
+type Reporter interface {
+	// OnFeatureStart is called when the feature starts being tested.
+	OnFeatureStart(name string)
+
+	// OnAssessmentResult is called when an assessment of the feature completes.
+	OnAssessmentResult(feature, assessment string, passed bool, duration time.Duration)
+
+	// OnFeatureEnd is called when the feature has been tested.
+	OnFeatureEnd(name string, passed bool)
+}
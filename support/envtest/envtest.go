@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package envtest provides a Cluster type that can be used to start and
+// stop a local control plane (etcd and kube-apiserver), using the
+// controller-runtime envtest package, during tests.
+package envtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	crenvtest "sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+)
+
+// Cluster represents a local control plane started with envtest.
+type Cluster struct {
+	name        string
+	env         *crenvtest.Environment
+	kubecfgFile string
+}
+
+// NewCluster returns an envtest cluster with the given name. The control plane
+// binaries are located using the envtest environment variables, such as
+// KUBEBUILDER_ASSETS.
+func NewCluster(name string) *Cluster {
+	return &Cluster{name: name, env: &crenvtest.Environment{}}
+}
+
+// WithEnvironment sets the envtest environment used to start the control
+// plane, i.e. to install CRDs (CRDDirectoryPaths) or to set the location of
+// the control plane binaries (BinaryAssetsDirectory).
+func (c *Cluster) WithEnvironment(env *crenvtest.Environment) *Cluster {
+	c.env = env
+	return c
+}
+
+// Create starts the control plane and returns the path of a kubeconfig file
+// containing the configuration of an administrator of the cluster.
+func (c *Cluster) Create() (string, error) {
+	log.Println("Starting envtest cluster ", c.name)
+	if _, err := c.env.Start(); err != nil {
+		return "", fmt.Errorf("failed to start envtest cluster: %w", err)
+	}
+
+	admin, err := c.env.AddUser(crenvtest.User{Name: "e2e-framework", Groups: []string{"system:masters"}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("envtest add user: %w", err)
+	}
+	kubecfg, err := admin.KubeConfig()
+	if err != nil {
+		return "", fmt.Errorf("envtest kubeconfig: %w", err)
+	}
+
+	file, err := ioutil.TempFile("", fmt.Sprintf("envtest-cluster-%s-kubecfg", c.name))
+	if err != nil {
+		return "", fmt.Errorf("envtest kubeconfig file: %w", err)
+	}
+	defer file.Close()
+
+	c.kubecfgFile = file.Name()
+
+	if _, err := file.Write(kubecfg); err != nil {
+		return "", fmt.Errorf("envtest kubeconfig file: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
+// GetKubeconfig returns the path of the kubeconfig file
+// associated with this envtest cluster
+func (c *Cluster) GetKubeconfig() string {
+	return c.kubecfgFile
+}
+
+// LoadImage does nothing, as envtest clusters do not run containers.
+func (c *Cluster) LoadImage(image string) error {
+	log.Printf("Skipping envtest Cluster.LoadImage: envtest cluster %s does not run containers: %s", c.name, image)
+	return nil
+}
+
+// Pause returns env.ErrUnsupported, envtest clusters cannot be paused.
+func (c *Cluster) Pause() error {
+	return fmt.Errorf("pause envtest cluster: %w", env.ErrUnsupported)
+}
+
+// Resume returns env.ErrUnsupported, envtest clusters cannot be paused.
+func (c *Cluster) Resume() error {
+	return fmt.Errorf("resume envtest cluster: %w", env.ErrUnsupported)
+}
+
+// Destroy stops the control plane and deletes its kubeconfig file.
+func (c *Cluster) Destroy() error {
+	log.Println("Stopping envtest cluster ", c.name)
+	if err := c.env.Stop(); err != nil {
+		return fmt.Errorf("failed to stop envtest cluster: %w", err)
+	}
+
+	log.Println("Removing kubeconfig file ", c.kubecfgFile)
+	if err := os.RemoveAll(c.kubecfgFile); err != nil {
+		return fmt.Errorf("envtest: remove kubeconfig failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package k3d provides a Cluster type that can be used to
+// create and manage a k3d cluster during tests.
+package k3d
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/vladimirvivien/gexe"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+)
+
+var k3dVersion = "v4.4.7"
+
+// Cluster represents a k3d cluster.
+type Cluster struct {
+	name        string
+	e           *gexe.Echo
+	kubecfgFile string
+	version     string
+	args        []string
+}
+
+// NewCluster returns a k3d cluster with the given name.
+func NewCluster(name string) *Cluster {
+	return &Cluster{name: name, e: gexe.New()}
+}
+
+// WithVersion set k3d version
+func (k *Cluster) WithVersion(ver string) *Cluster {
+	k.version = ver
+	return k
+}
+
+// WithArgs appends arguments (i.e. --agents 2, --servers 1, --image rancher/k3s:v1.21.2-k3s1)
+// to the `k3d cluster create` command.
+func (k *Cluster) WithArgs(args ...string) *Cluster {
+	k.args = append(k.args, args...)
+	return k
+}
+
+// Create creates the k3d cluster and returns the path of a
+// kubeconfig file containing the cluster configuration.
+func (k *Cluster) Create() (string, error) {
+	log.Println("Creating k3d cluster ", k.name)
+	if err := k.findOrInstallK3d(k.e); err != nil {
+		return "", err
+	}
+
+	if strings.Contains(k.e.Run("k3d cluster list --no-headers"), k.name) {
+		log.Println("Skipping k3d Cluster.Create: cluster already created: ", k.name)
+		return "", nil
+	}
+
+	cmd := strings.Join(append([]string{"k3d", "cluster", "create", k.name}, k.args...), " ")
+	log.Println("launching:", cmd)
+	p := k.e.RunProc(cmd)
+	if p.Err() != nil {
+		return "", fmt.Errorf("failed to create k3d cluster: %s : %s", p.Err(), p.Result())
+	}
+
+	// grab kubeconfig file for cluster
+	p = k.e.StartProc(fmt.Sprintf(`k3d kubeconfig get %s`, k.name))
+	if p.Err() != nil {
+		return "", fmt.Errorf("k3d kubeconfig get: %s: %w", p.Result(), p.Err())
+	}
+
+	file, err := ioutil.TempFile("", fmt.Sprintf("k3d-cluster-%s-kubecfg", k.name))
+	if err != nil {
+		return "", fmt.Errorf("k3d kubeconfig file: %w", err)
+	}
+	defer file.Close()
+
+	k.kubecfgFile = file.Name()
+
+	if n, err := io.Copy(file, p.Out()); n == 0 || err != nil {
+		return "", fmt.Errorf("k3d kubecfg file: bytes copied: %d: %w", n, err)
+	}
+
+	return file.Name(), nil
+}
+
+// GetKubeconfig returns the path of the kubeconfig file
+// associated with this k3d cluster
+func (k *Cluster) GetKubeconfig() string {
+	return k.kubecfgFile
+}
+
+// GetKubeCtlContext returns the kubectl context of the cluster.
+func (k *Cluster) GetKubeCtlContext() string {
+	return fmt.Sprintf("k3d-%s", k.name)
+}
+
+// LoadImage imports the image, from the local docker daemon, into the cluster.
+func (k *Cluster) LoadImage(image string) error {
+	if err := k.findOrInstallK3d(k.e); err != nil {
+		return err
+	}
+
+	p := k.e.RunProc(fmt.Sprintf(`k3d image import %s --cluster %s`, image, k.name))
+	if p.Err() != nil {
+		return fmt.Errorf("k3d image import: %s: %w", p.Result(), p.Err())
+	}
+	return nil
+}
+
+// Pause returns env.ErrUnsupported, k3d clusters cannot be paused.
+func (k *Cluster) Pause() error {
+	return fmt.Errorf("pause k3d cluster: %w", env.ErrUnsupported)
+}
+
+// Resume returns env.ErrUnsupported, k3d clusters cannot be paused.
+func (k *Cluster) Resume() error {
+	return fmt.Errorf("resume k3d cluster: %w", env.ErrUnsupported)
+}
+
+// Destroy deletes the k3d cluster and its kubeconfig file.
+func (k *Cluster) Destroy() error {
+	log.Println("Destroying k3d cluster ", k.name)
+	if err := k.findOrInstallK3d(k.e); err != nil {
+		return err
+	}
+
+	p := k.e.RunProc(fmt.Sprintf(`k3d cluster delete %s`, k.name))
+	if p.Err() != nil {
+		return fmt.Errorf("failed to delete k3d cluster: %s: %s", p.Err(), p.Result())
+	}
+
+	log.Println("Removing kubeconfig file ", k.kubecfgFile)
+	if err := os.RemoveAll(k.kubecfgFile); err != nil {
+		return fmt.Errorf("k3d: remove kubeconfig failed: %w", err)
+	}
+
+	return nil
+}
+
+func (k *Cluster) findOrInstallK3d(e *gexe.Echo) error {
+	if e.Prog().Avail("k3d") == "" {
+		log.Println(`k3d not found, installing with GO111MODULE="on" go get github.com/rancher/k3d/v4@`, k3dVersion)
+		if err := k.installK3d(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *Cluster) installK3d(e *gexe.Echo) error {
+	if k.version != "" {
+		k3dVersion = k.version
+	}
+
+	log.Println("installing: go get github.com/rancher/k3d/v4@", k3dVersion)
+	p := e.SetEnv("GO111MODULE", "on").RunProc(fmt.Sprintf("go get github.com/rancher/k3d/v4@%s", k3dVersion))
+	if p.Err() != nil {
+		return fmt.Errorf("failed to install k3d: %s", p.Err())
+	}
+
+	if !p.IsSuccess() || p.ExitCode() != 0 {
+		return fmt.Errorf("failed to install k3d: %s", p.Result())
+	}
+
+	p = e.RunProc("echo $PATH:$GOPATH/bin")
+	if p.Err() != nil {
+		return fmt.Errorf("failed to install k3d: %s", p.Err())
+	}
+
+	e.SetEnv("PATH", p.Result())
+
+	return nil
+}
@@ -21,7 +21,9 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
+	"os/exec"
 	"strings"
 
 	"github.com/vladimirvivien/gexe"
@@ -34,6 +36,10 @@ type Cluster struct {
 	e           *gexe.Echo
 	kubecfgFile string
 	version     string
+	httpProxy   string
+	httpsProxy  string
+	noProxy     string
+	config      string
 }
 
 func NewCluster(name string) *Cluster {
@@ -46,6 +52,37 @@ func (k *Cluster) WithVersion(ver string) *Cluster {
 	return k
 }
 
+// WithProxy sets the HTTP_PROXY and NO_PROXY values passed to kind when
+// creating the cluster. When not set, the values are taken from the
+// HTTP_PROXY and NO_PROXY (or http_proxy and no_proxy) environment variables.
+func (k *Cluster) WithProxy(httpProxy, noProxy string) *Cluster {
+	k.httpProxy = httpProxy
+	k.noProxy = noProxy
+	return k
+}
+
+// WithHTTPSProxy sets the HTTPS_PROXY value passed to kind when creating the
+// cluster. When not set, the value is taken from the HTTPS_PROXY (or
+// https_proxy) environment variable.
+func (k *Cluster) WithHTTPSProxy(httpsProxy string) *Cluster {
+	k.httpsProxy = httpsProxy
+	return k
+}
+
+// WithConfig sets the kind configuration, in YAML, used to create the
+// cluster, i.e. to create a multi-node cluster:
+//
+//	kind: Cluster
+//	apiVersion: kind.x-k8s.io/v1alpha4
+//	nodes:
+//	- role: control-plane
+//	- role: worker
+//	- role: worker
+func (k *Cluster) WithConfig(configYAML string) *Cluster {
+	k.config = configYAML
+	return k
+}
+
 func (k *Cluster) Create() (string, error) {
 	log.Println("Creating kind cluster ", k.name)
 	// is kind program available
@@ -58,11 +95,19 @@ func (k *Cluster) Create() (string, error) {
 		return "", nil
 	}
 
-	// create kind cluster using kind-cluster-docker.yaml config file
-	log.Println("launching: kind create cluster --name", k.name)
-	p := k.e.RunProc(fmt.Sprintf(`kind create cluster --name %s`, k.name))
-	if p.Err() != nil {
-		return "", fmt.Errorf("failed to create kind cluster: %s : %s", p.Err(), p.Result())
+	configFile, err := k.writeConfig()
+	if err != nil {
+		return "", err
+	}
+	if configFile != "" {
+		// kind only reads the config file while creating the cluster
+		defer os.Remove(configFile)
+	}
+
+	cmd := k.createCommand(configFile)
+	log.Println("launching:", strings.Join(cmd.Args, " "))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to create kind cluster: %s : %s", err, out)
 	}
 
 	clusters := k.e.Run("kind get clusters")
@@ -70,7 +115,7 @@ func (k *Cluster) Create() (string, error) {
 
 	// grab kubeconfig file for cluster
 	kubecfg := fmt.Sprintf("%s-kubecfg", k.name)
-	p = k.e.StartProc(fmt.Sprintf(`kind get kubeconfig --name %s`, k.name))
+	p := k.e.StartProc(fmt.Sprintf(`kind get kubeconfig --name %s`, k.name))
 	if p.Err() != nil {
 		return "", fmt.Errorf("kind get kubeconfig: %s: %w", p.Result(), p.Err())
 	}
@@ -90,6 +135,39 @@ func (k *Cluster) Create() (string, error) {
 	return file.Name(), nil
 }
 
+// writeConfig writes the kind configuration, when set, to a temporary
+// file and returns its path, which the caller must remove
+func (k *Cluster) writeConfig() (string, error) {
+	if k.config == "" {
+		return "", nil
+	}
+
+	file, err := ioutil.TempFile("", fmt.Sprintf("kind-config-%s", k.name))
+	if err != nil {
+		return "", fmt.Errorf("kind config file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(k.config); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("kind config file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// createCommand returns the kind command creating the cluster, with the
+// config file when not empty. The proxy settings are only set in the
+// environment of the kind process.
+func (k *Cluster) createCommand(configFile string) *exec.Cmd {
+	args := []string{"create", "cluster", "--name", k.name}
+	if configFile != "" {
+		args = append(args, "--config", configFile)
+	}
+	cmd := exec.Command("kind", args...)
+	cmd.Env = append(os.Environ(), k.proxyEnv()...)
+	return cmd
+}
+
 // GetKubeconfig returns the path of the kubeconfig file
 // associated with this kind cluster
 func (k *Cluster) GetKubeconfig() string {
@@ -120,6 +198,123 @@ func (k *Cluster) Destroy() error {
 	return nil
 }
 
+// LoadImageArchive loads the images of the archive, i.e. created with
+// docker save, into the nodes of the kind cluster.
+func (k *Cluster) LoadImageArchive(archivePath string) error {
+	if err := k.findOrInstallKind(k.e); err != nil {
+		return err
+	}
+
+	p := k.e.RunProc(fmt.Sprintf(`kind load image-archive %s --name %s`, archivePath, k.name))
+	if p.Err() != nil {
+		return fmt.Errorf("kind load image-archive: %s: %w", p.Result(), p.Err())
+	}
+
+	return nil
+}
+
+// ExportLogs exports the logs of the kind cluster nodes (i.e. kubelet,
+// API server, etcd) to destDir using kind export logs.
+func (k *Cluster) ExportLogs(destDir string) error {
+	if err := k.findOrInstallKind(k.e); err != nil {
+		return err
+	}
+
+	p := k.e.RunProc(fmt.Sprintf(`kind export logs %s --name %s`, destDir, k.name))
+	if p.Err() != nil {
+		return fmt.Errorf("kind export logs: %s: %w", p.Result(), p.Err())
+	}
+
+	return nil
+}
+
+// Pause pauses the docker containers of all nodes of the kind cluster
+// to simulate an unavailable cluster (i.e. network partition).
+// Along with Resume, it implements env.Pauser.
+func (k *Cluster) Pause() error {
+	log.Println("Pausing kind cluster ", k.name)
+	return k.runOnNodes("docker pause")
+}
+
+// Resume unpauses the docker containers of all nodes of the kind
+// cluster previously paused with Pause.
+func (k *Cluster) Resume() error {
+	log.Println("Resuming kind cluster ", k.name)
+	return k.runOnNodes("docker unpause")
+}
+
+// runOnNodes runs the command with the names of the cluster's
+// node containers as arguments.
+func (k *Cluster) runOnNodes(cmd string) error {
+	if err := k.findOrInstallKind(k.e); err != nil {
+		return err
+	}
+
+	p := k.e.RunProc(fmt.Sprintf(`kind get nodes --name %s`, k.name))
+	if p.Err() != nil {
+		return fmt.Errorf("kind get nodes: %s: %w", p.Result(), p.Err())
+	}
+
+	nodes := strings.Fields(p.Result())
+	if len(nodes) == 0 {
+		return fmt.Errorf("kind: no nodes found for cluster %s", k.name)
+	}
+
+	p = k.e.RunProc(fmt.Sprintf("%s %s", cmd, strings.Join(nodes, " ")))
+	if p.Err() != nil {
+		return fmt.Errorf("%s: %s: %w", cmd, p.Result(), p.Err())
+	}
+
+	return nil
+}
+
+// proxyEnv returns the proxy environment variables used by kind,
+// falling back to the ambient values when not set on the cluster.
+func (k *Cluster) proxyEnv() []string {
+	proxies := []struct{ name, value string }{
+		{name: "HTTP_PROXY", value: k.httpProxy},
+		{name: "HTTPS_PROXY", value: k.httpsProxy},
+		{name: "NO_PROXY", value: k.noProxy},
+	}
+	var env []string
+	for _, proxy := range proxies {
+		value := proxy.value
+		if value == "" {
+			value = lookupEnv(proxy.name, strings.ToLower(proxy.name))
+		}
+		if value == "" {
+			continue
+		}
+		log.Printf("kind: using %s=%s", proxy.name, redactProxy(value))
+		env = append(env, fmt.Sprintf("%s=%s", proxy.name, value))
+	}
+	return env
+}
+
+// redactProxy returns the proxy value with the password of its URL, if
+// any, redacted so it can be logged.
+func redactProxy(value string) string {
+	u, err := url.Parse(value)
+	if err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	// a value that is not a URL, i.e. user:pass@proxy:3128 without scheme, may still hold credentials
+	if err != nil || strings.Contains(value, "@") {
+		return "xxxxx"
+	}
+	return value
+}
+
+// lookupEnv returns the value of the first environment variable set
+func lookupEnv(names ...string) string {
+	for _, name := range names {
+		if val := os.Getenv(name); val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
 func (k *Cluster) findOrInstallKind(e *gexe.Echo) error {
 	if e.Prog().Avail("kind") == "" {
 		log.Println(`kind not found, installing with GO111MODULE="on" go get sigs.k8s.io/kind@v0.11.0`)
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// ociArchiveVersion is the first kind version given OCI layouts as OCI
+// archives. Older versions are given docker archives converted with skopeo.
+var ociArchiveVersion = semver.MustParse("0.20.0")
+
+// LoadImageOCILayout loads the images of the OCI layout directory, i.e.
+// produced by buildah or skopeo, into the nodes of the kind cluster. The
+// directory is archived as is when the installed kind version supports OCI
+// archives and converted to a docker archive with skopeo otherwise.
+func (k *Cluster) LoadImageOCILayout(layoutDir string) error {
+	if err := k.findOrInstallKind(k.e); err != nil {
+		return err
+	}
+
+	version, err := parseKindVersion(k.e.Run("kind version"))
+	if err != nil {
+		return err
+	}
+
+	archive, err := ioutil.TempFile("", fmt.Sprintf("kind-image-%s-*.tar", k.name))
+	if err != nil {
+		return fmt.Errorf("kind image archive: %w", err)
+	}
+	defer os.Remove(archive.Name())
+
+	if version.GTE(ociArchiveVersion) {
+		err = tarDir(layoutDir, archive)
+		if closeErr := archive.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("archive OCI layout %s: %w", layoutDir, err)
+		}
+	} else {
+		archive.Close()
+		log.Printf("kind %s does not load OCI archives, converting %s with skopeo", version, layoutDir)
+		p := k.e.RunProc(fmt.Sprintf(`skopeo copy oci:%s docker-archive:%s`, layoutDir, archive.Name()))
+		if p.Err() != nil {
+			return fmt.Errorf("skopeo copy: %s: %w", p.Result(), p.Err())
+		}
+	}
+
+	return k.LoadImageArchive(archive.Name())
+}
+
+// parseKindVersion returns the version from the output
+// of kind version, i.e. kind v0.11.0 go1.16.4 linux/amd64
+func parseKindVersion(output string) (semver.Version, error) {
+	fields := strings.Fields(output)
+	if len(fields) < 2 || fields[0] != "kind" {
+		return semver.Version{}, fmt.Errorf("unexpected kind version output %q", output)
+	}
+	version, err := semver.ParseTolerant(fields[1])
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("kind version: %w", err)
+	}
+	return version, nil
+}
+
+// tarDir writes the regular files and directories of dir to w as a tar
+// archive, with paths relative to dir
+func tarDir(dir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir || !(info.Mode().IsRegular() || info.IsDir()) {
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseKindVersion(t *testing.T) {
+	tests := []struct {
+		output     string
+		expected   string
+		shouldFail bool
+	}{
+		{output: "kind v0.11.0 go1.16.4 linux/amd64\n", expected: "0.11.0"},
+		{output: "kind v0.20.0-alpha+abc go1.20.4 darwin/arm64", expected: "0.20.0-alpha+abc"},
+		{output: "command not found: kind", shouldFail: true},
+		{output: "kind vnext", shouldFail: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.output, func(t *testing.T) {
+			version, err := parseKindVersion(test.output)
+			if test.shouldFail {
+				if err == nil {
+					t.Errorf("expected an error, got version %s", version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version.String() != test.expected {
+				t.Errorf("expected version %s, got %s", test.expected, version)
+			}
+		})
+	}
+}
+
+func TestTarDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"oci-layout":          `{"imageLayoutVersion": "1.0.0"}`,
+		"index.json":          `{"schemaVersion": 2, "manifests": []}`,
+		"blobs/sha256/abc123": "layer",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tarDir(dir, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	archived := make(map[string]string)
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archived[header.Name] = string(content)
+	}
+
+	if len(archived) != len(files) {
+		t.Errorf("expected files %v, got %v", files, archived)
+	}
+	for name, content := range files {
+		if archived[name] != content {
+			t.Errorf("file %s: expected %q, got %q", name, content, archived[name])
+		}
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package minikube provides a Cluster type that can be used to
+// create and manage a minikube cluster during tests.
+package minikube
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/vladimirvivien/gexe"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+)
+
+var minikubeVersion = "v1.21.0"
+
+// Cluster represents a minikube cluster, identified by its profile name.
+type Cluster struct {
+	name        string
+	e           *gexe.Echo
+	kubecfgFile string
+	version     string
+	driver      string
+	cpus        int
+	memory      string
+}
+
+// CreateOptions configures the resources of the minikube cluster.
+// Zero values leave the minikube defaults.
+type CreateOptions struct {
+	// Driver is the minikube driver, i.e. docker or kvm2.
+	Driver string
+	// CPUs is the number of CPUs allocated to the cluster.
+	CPUs int
+	// Memory is the amount of memory allocated to the cluster, i.e. 4g.
+	Memory string
+}
+
+// NewCluster returns a minikube cluster that uses name as its profile.
+func NewCluster(name string) *Cluster {
+	return &Cluster{name: name, e: gexe.New()}
+}
+
+// WithVersion sets the minikube version installed when the
+// minikube binary is not found.
+func (m *Cluster) WithVersion(ver string) *Cluster {
+	m.version = ver
+	return m
+}
+
+// WithDriver sets the minikube driver (i.e. docker, kvm2) used to create the cluster.
+func (m *Cluster) WithDriver(driver string) *Cluster {
+	m.driver = driver
+	return m
+}
+
+// WithCPUs sets the number of CPUs allocated to the cluster.
+func (m *Cluster) WithCPUs(cpus int) *Cluster {
+	m.cpus = cpus
+	return m
+}
+
+// WithMemory sets the amount of memory (i.e. 4g, 4096mb) allocated to the cluster.
+func (m *Cluster) WithMemory(memory string) *Cluster {
+	m.memory = memory
+	return m
+}
+
+// WithCreateOptions sets the driver, CPUs and memory of the cluster.
+func (m *Cluster) WithCreateOptions(opts CreateOptions) *Cluster {
+	return m.WithDriver(opts.Driver).WithCPUs(opts.CPUs).WithMemory(opts.Memory)
+}
+
+// Create starts the minikube cluster and returns the path of a kubeconfig
+// file containing only the configuration of this cluster.
+func (m *Cluster) Create() (string, error) {
+	log.Println("Creating minikube cluster ", m.name)
+	if err := m.findOrInstallMinikube(m.e); err != nil {
+		return "", err
+	}
+
+	if m.kubecfgFile == "" {
+		file, err := ioutil.TempFile("", fmt.Sprintf("minikube-cluster-%s-kubecfg", m.name))
+		if err != nil {
+			return "", fmt.Errorf("minikube kubeconfig file: %w", err)
+		}
+		file.Close()
+		m.kubecfgFile = file.Name()
+	}
+
+	exists, err := m.exists()
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		log.Println("Skipping minikube Cluster.Create: cluster already created: ", m.name)
+		if out, err := m.command("update-context", "--profile", m.name).CombinedOutput(); err != nil {
+			return "", fmt.Errorf("minikube update-context: %s: %w", out, err)
+		}
+		return m.kubecfgFile, nil
+	}
+
+	cmd := m.command(m.startArgs()...)
+	log.Println("launching:", strings.Join(cmd.Args, " "))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to create minikube cluster: %s : %s", err, out)
+	}
+
+	return m.kubecfgFile, nil
+}
+
+func (m *Cluster) startArgs() []string {
+	args := []string{"start", "--profile", m.name}
+	if m.driver != "" {
+		args = append(args, "--driver", m.driver)
+	}
+	if m.cpus > 0 {
+		args = append(args, "--cpus", fmt.Sprintf("%d", m.cpus))
+	}
+	if m.memory != "" {
+		args = append(args, "--memory", m.memory)
+	}
+	return args
+}
+
+// command returns a minikube command that writes the cluster configuration
+// in the cluster's kubeconfig file. KUBECONFIG is only set for the minikube
+// process, leaving the environment of the test process unchanged.
+func (m *Cluster) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("minikube", args...)
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+m.kubecfgFile)
+	return cmd
+}
+
+// exists reports whether the minikube profile of the cluster exists
+func (m *Cluster) exists() (bool, error) {
+	var profiles struct {
+		Valid   []struct{ Name string }
+		Invalid []struct{ Name string }
+	}
+	// the profiles are decoded before checking the command error,
+	// as minikube may exit with an error when no profile exists
+	out, err := m.command("profile", "list", "--output", "json").Output()
+	if decodeErr := json.Unmarshal(out, &profiles); decodeErr != nil {
+		if err != nil {
+			return false, fmt.Errorf("minikube profile list: %w", err)
+		}
+		return false, fmt.Errorf("minikube profile list: %w", decodeErr)
+	}
+	for _, profile := range append(profiles.Valid, profiles.Invalid...) {
+		if profile.Name == m.name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetKubeconfig returns the path of the kubeconfig file
+// associated with this minikube cluster
+func (m *Cluster) GetKubeconfig() string {
+	return m.kubecfgFile
+}
+
+// GetKubeCtlContext returns the kubectl context of the cluster,
+// which minikube names after the profile.
+func (m *Cluster) GetKubeCtlContext() string {
+	return m.name
+}
+
+// LoadImage loads the image, from the local container runtime, into the cluster.
+func (m *Cluster) LoadImage(image string) error {
+	if err := m.findOrInstallMinikube(m.e); err != nil {
+		return err
+	}
+
+	p := m.e.RunProc(fmt.Sprintf(`minikube image load %s --profile %s`, image, m.name))
+	if p.Err() != nil {
+		return fmt.Errorf("minikube image load: %s: %w", p.Result(), p.Err())
+	}
+	return nil
+}
+
+// Pause returns env.ErrUnsupported, minikube clusters cannot be paused.
+func (m *Cluster) Pause() error {
+	return fmt.Errorf("pause minikube cluster: %w", env.ErrUnsupported)
+}
+
+// Resume returns env.ErrUnsupported, minikube clusters cannot be paused.
+func (m *Cluster) Resume() error {
+	return fmt.Errorf("resume minikube cluster: %w", env.ErrUnsupported)
+}
+
+// Destroy deletes the minikube cluster and its kubeconfig file.
+func (m *Cluster) Destroy() error {
+	log.Println("Destroying minikube cluster ", m.name)
+	if err := m.findOrInstallMinikube(m.e); err != nil {
+		return err
+	}
+
+	p := m.e.RunProc(fmt.Sprintf(`minikube delete --profile %s`, m.name))
+	if p.Err() != nil {
+		return fmt.Errorf("failed to delete minikube cluster: %s: %s", p.Err(), p.Result())
+	}
+
+	log.Println("Removing kubeconfig file ", m.kubecfgFile)
+	if err := os.RemoveAll(m.kubecfgFile); err != nil {
+		return fmt.Errorf("minikube: remove kubeconfig failed: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Cluster) findOrInstallMinikube(e *gexe.Echo) error {
+	if e.Prog().Avail("minikube") == "" {
+		log.Println("minikube not found, installing minikube", minikubeVersion)
+		if err := m.installMinikube(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installMinikube downloads the minikube release binary in $GOPATH/bin
+func (m *Cluster) installMinikube(e *gexe.Echo) error {
+	if m.version != "" {
+		minikubeVersion = m.version
+	}
+
+	url := fmt.Sprintf(
+		"https://storage.googleapis.com/minikube/releases/%s/minikube-%s-%s",
+		minikubeVersion, runtime.GOOS, runtime.GOARCH,
+	)
+	log.Println("installing: minikube from", url)
+
+	gopath := e.Run("go env GOPATH")
+	if gopath == "" {
+		return fmt.Errorf("failed to install minikube: GOPATH not found")
+	}
+	bin := fmt.Sprintf("%s/bin/minikube", gopath)
+
+	p := e.RunProc(fmt.Sprintf("curl -sSLo %s %s", bin, url))
+	if p.Err() != nil {
+		return fmt.Errorf("failed to install minikube: %s", p.Err())
+	}
+
+	if !p.IsSuccess() || p.ExitCode() != 0 {
+		return fmt.Errorf("failed to install minikube: %s", p.Result())
+	}
+
+	if err := os.Chmod(bin, 0755); err != nil {
+		return fmt.Errorf("failed to install minikube: %w", err)
+	}
+
+	p = e.RunProc(fmt.Sprintf("echo $PATH:%s/bin", gopath))
+	if p.Err() != nil {
+		return fmt.Errorf("failed to install minikube: %s", p.Err())
+	}
+
+	e.SetEnv("PATH", p.Result())
+
+	return nil
+}
@@ -0,0 +1,67 @@
+//go:build minikube
+// +build minikube
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minikube
+
+import (
+	"os"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestCluster_CreateDestroy(t *testing.T) {
+	kubeconfigEnv := os.Getenv("KUBECONFIG")
+	cluster := NewCluster("e2e-framework-minikube").WithCreateOptions(CreateOptions{CPUs: 2, Memory: "2g"})
+	kubecfg, err := cluster.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("KUBECONFIG") != kubeconfigEnv {
+		t.Errorf("KUBECONFIG of the test process changed to %s", os.Getenv("KUBECONFIG"))
+	}
+	assertContext(t, kubecfg, cluster.GetKubeCtlContext())
+
+	// an existing cluster is reused, with its own kubeconfig file
+	existing := NewCluster("e2e-framework-minikube")
+	existingKubecfg, err := existing.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(existingKubecfg)
+	assertContext(t, existingKubecfg, existing.GetKubeCtlContext())
+
+	if err := cluster.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(kubecfg); !os.IsNotExist(err) {
+		t.Errorf("kubeconfig file %s was not removed", kubecfg)
+	}
+}
+
+func assertContext(t *testing.T, kubecfg, context string) {
+	t.Helper()
+	cfg, err := clientcmd.LoadFromFile(kubecfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.Contexts[context]; !ok {
+		t.Errorf("kubeconfig %s does not contain context %s", kubecfg, context)
+	}
+}